@@ -0,0 +1,303 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+// manifestName is the name of the manifest in a backup archive.
+// It's the last entry of the archive, so the files can be streamed into the archive.
+const manifestName = "fztea-manifest.json"
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// manifest describes the content of a backup archive.
+type manifest struct {
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Device  map[string]string `json:"device"`
+	Files   []manifestFile    `json:"files"`
+}
+
+// manifestFile is a single file of the backup.
+type manifestFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	MD5  string `json:"md5"`
+}
+
+var backupFlags struct {
+	output   string
+	internal bool
+	filter   filterFlags
+}
+
+var backupCmd = &coral.Command{
+	Use:          "backup",
+	Short:        "Back up the sd card of the flipper to a tar.gz archive",
+	Example:      "  fztea backup -o flipper-2026-10-17.tar.gz --exclude /ext/update",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE:         backup,
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupFlags.output, "output", "o", "", "archive to write (default: flipper-<date>.tar.gz)")
+	backupCmd.Flags().BoolVar(&backupFlags.internal, "int", false, "also back up the internal storage (/int)")
+	backupFlags.filter.register(backupCmd)
+	addQuietFlag(backupCmd)
+}
+
+func backup(cmd *coral.Command, _ []string) error {
+	if err := backupFlags.filter.validate(); err != nil {
+		return err
+	}
+	output := backupFlags.output
+	if output == "" {
+		output = fmt.Sprintf("flipper-%s.tar.gz", time.Now().Format("2006-01-02"))
+	}
+	roots := []string{"/ext"}
+	if backupFlags.internal {
+		roots = append(roots, "/int")
+	}
+
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	t, err := newTransfer(cmd.Context(), fz)
+	if err != nil {
+		return err
+	}
+	info, err := fz.DeviceInfo(t.ctx)
+	if err != nil {
+		return fmt.Errorf("could not get device info: %w", err)
+	}
+
+	// write to a temporary file first, so an aborted backup doesn't look like a complete one
+	f, err := os.CreateTemp(filepath.Dir(output), ".fztea-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	b := &backupWriter{
+		transfer: t,
+		gz:       gzip.NewWriter(f),
+		output:   output,
+		manifest: manifest{
+			Version: manifestVersion,
+			Created: time.Now().UTC(),
+			Device:  info,
+		},
+	}
+	b.tw = tar.NewWriter(b.gz)
+	for _, root := range roots {
+		if err := b.walk(root); err != nil {
+			return err
+		}
+	}
+	if err := b.close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), output); err != nil {
+		return err
+	}
+	if !transferFlags.quiet {
+		fmt.Printf("backed up %d files to %s\n", len(b.manifest.Files), output)
+	}
+	return t.err()
+}
+
+// backupWriter streams the files of the flipper into a tar.gz archive.
+type backupWriter struct {
+	*transfer
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	output   string
+	manifest manifest
+}
+
+// walk adds a directory and its content to the archive.
+// Errors of single files are reported, errors of the archive are returned.
+func (b *backupWriter) walk(dir string) error {
+	files, err := b.fz.List(b.ctx, dir)
+	if err != nil {
+		b.fail(err)
+		return nil
+	}
+	if err := b.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     archivePath(dir) + "/",
+		Mode:     0o755,
+		ModTime:  b.manifest.Created,
+	}); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		p := path.Join(dir, f.Name())
+		switch {
+		case backupFlags.filter.excluded(p):
+			continue
+		case f.IsDir():
+			err = b.walk(p)
+		case backupFlags.filter.included(p):
+			err = b.addFile(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFile reads a file from the flipper and adds it to the archive.
+func (b *backupWriter) addFile(p string) error {
+	bar := newProgressBar(p)
+	var buf bytes.Buffer
+	err := b.retry(func() error {
+		buf.Reset()
+		_, err := b.fz.ReadFile(b.ctx, p, &buf, bar.update)
+		return err
+	})
+	bar.clear()
+	if err != nil {
+		b.fail(err)
+		return nil
+	}
+
+	if err := b.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     archivePath(p),
+		Size:     int64(buf.Len()),
+		Mode:     0o644,
+		ModTime:  b.manifest.Created,
+	}); err != nil {
+		return err
+	}
+	sum := md5.Sum(buf.Bytes()) //nolint:gosec
+	if _, err := b.tw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	b.manifest.Files = append(b.manifest.Files, manifestFile{
+		Path: p,
+		Size: int64(buf.Len()),
+		MD5:  hex.EncodeToString(sum[:]),
+	})
+	b.copied(p, b.output, int64(buf.Len()))
+	return nil
+}
+
+// close appends the manifest and closes the archive.
+func (b *backupWriter) close() error {
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := b.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     manifestName,
+		Size:     int64(len(data)),
+		Mode:     0o644,
+		ModTime:  b.manifest.Created,
+	}); err != nil {
+		return err
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
+
+// archivePath returns the path of a file on the flipper inside the archive, e.g. ext/subghz/gate.sub.
+func archivePath(p string) string {
+	return strings.TrimPrefix(path.Clean(p), "/")
+}
+
+// filterFlags select files by glob patterns.
+// Patterns without a slash match the name of a file, e.g. *.sub.
+// Patterns with a slash match the whole path or a parent directory, e.g. /ext/subghz or /ext/*/*.sub.
+type filterFlags struct {
+	include []string
+	exclude []string
+}
+
+// register adds the filter flags to a command.
+func (ff *filterFlags) register(cmd *coral.Command) {
+	cmd.Flags().StringSliceVar(&ff.include, "include", nil, "only files matching one of these patterns")
+	cmd.Flags().StringSliceVar(&ff.exclude, "exclude", nil, "skip files matching one of these patterns")
+}
+
+// validate checks the syntax of the patterns.
+func (ff *filterFlags) validate() error {
+	for _, patterns := range [][]string{ff.include, ff.exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// excluded returns true if the path matches one of the exclude patterns.
+func (ff *filterFlags) excluded(p string) bool {
+	for _, pattern := range ff.exclude {
+		if matchPattern(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// included returns true if a path matches one of the include patterns or if there are none.
+// A directory which isn't included might still contain included files.
+func (ff *filterFlags) included(p string) bool {
+	if len(ff.include) == 0 {
+		return true
+	}
+	for _, pattern := range ff.include {
+		if matchPattern(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a single filter pattern against a path.
+func matchPattern(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	pattern = path.Clean(pattern)
+	for ; p != "/" && p != "."; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jon4hz/fztea/flipperui"
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var broadcastFlags struct {
+	to []string
+}
+
+var broadcastCmd = &coral.Command{
+	Use:          "broadcast key...",
+	Short:        "Send the same keys to multiple flippers at once",
+	Example:      "  fztea broadcast --to alpha --to bravo --settle 500ms down down enter",
+	Args:         coral.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         broadcast,
+}
+
+func init() {
+	broadcastCmd.Flags().StringSliceVar(&broadcastFlags.to, "to", nil, "names or ports of the flippers (default: all attached flippers)")
+	addFleetFlags(broadcastCmd)
+}
+
+func broadcast(cmd *coral.Command, keys []string) error {
+	// fail early instead of after connecting to all flippers
+	for _, key := range keys {
+		if event, _ := flipperui.ParseKey(key); event == -1 {
+			return fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	targets, err := fleetTargets(broadcastFlags.to)
+	if err != nil {
+		return err
+	}
+
+	var failed bool
+	var flippers []*recfz.FlipperZero
+	names := make(map[*recfz.FlipperZero]string)
+	for _, target := range targets {
+		fz, err := recfz.NewFlipperZero(
+			append(target.opts,
+				recfz.WithContext(cmd.Context()),
+				recfz.WithReconnectPolicy(reconnectPolicy()),
+				recfz.WithLogger(log.New(io.Discard, "", 0)),
+			)...,
+		)
+		if err == nil {
+			err = fz.Connect()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: could not connect: %s\n", target.name, err)
+			failed = true
+			continue
+		}
+		defer fz.Close()
+		flippers = append(flippers, fz)
+		names[fz] = target.name
+	}
+	if len(flippers) == 0 {
+		return errors.New("could not connect to any flipper")
+	}
+
+	// the results are printed as soon as they arrive, so the columns are padded manually
+	var keyWidth, nameWidth int
+	for _, key := range keys {
+		keyWidth = max(keyWidth, len(key))
+	}
+	for _, name := range names {
+		nameWidth = max(nameWidth, len(name))
+	}
+
+	fleet := recfz.NewFleet(flippers, fleetOpts()...)
+	for _, key := range keys {
+		results, err := sendToFleet(cmd.Context(), fleet, key)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			failed = failed || r.Err != nil
+			fmt.Printf("%-*s  %-*s  %s (%s)\n", keyWidth, key, nameWidth, names[r.Flipper], formatResult(r), r.Duration.Round(time.Millisecond))
+		}
+	}
+	if failed {
+		return errors.New("some flippers failed")
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// controller hands out the input control of the flipper to exactly one session at a time.
+// All other sessions can only watch.
+type controller struct {
+	sync.Mutex
+	// viewers holds all connected sessions in the order they connected.
+	viewers []*viewer
+	// driver is the session that currently controls the flipper.
+	driver *viewer
+	// queue holds the sessions that requested control, oldest first.
+	queue []*viewer
+}
+
+// viewer represents a single session connected to the controller.
+type viewer struct {
+	name    string
+	updates chan controlMsg
+}
+
+// controlMsg is sent to every viewer whenever the control state changes.
+type controlMsg struct {
+	// driver is the name of the session that currently controls the flipper.
+	driver string
+	// isDriver is true if the receiving viewer is the driver.
+	isDriver bool
+	// requested is true if the receiving viewer is waiting for control.
+	requested bool
+	// waiting is the number of sessions waiting for control.
+	waiting int
+	// viewers is the total number of connected sessions.
+	viewers int
+}
+
+// newController returns a new controller.
+func newController() *controller {
+	return &controller{}
+}
+
+// Join adds a new viewer. The first viewer automatically becomes the driver.
+func (c *controller) Join(name string) *viewer {
+	c.Lock()
+	defer c.Unlock()
+	v := &viewer{
+		name:    name,
+		updates: make(chan controlMsg, 1),
+	}
+	c.viewers = append(c.viewers, v)
+	if c.driver == nil {
+		c.driver = v
+	}
+	c.notify()
+	return v
+}
+
+// Leave removes a viewer. If the viewer was driving, control is passed on.
+// The updates of the viewer are closed, which stops listenControl.
+func (c *controller) Leave(v *viewer) {
+	c.Lock()
+	defer c.Unlock()
+	if indexOfViewer(c.viewers, v) < 0 {
+		return
+	}
+	c.viewers = removeViewer(c.viewers, v)
+	c.queue = removeViewer(c.queue, v)
+	if c.driver == v {
+		c.driver = c.next()
+	}
+	close(v.updates)
+	c.notify()
+}
+
+// Request puts the viewer in the queue of sessions waiting for control.
+// If nobody is driving, the viewer gets control immediately.
+func (c *controller) Request(v *viewer) {
+	c.Lock()
+	defer c.Unlock()
+	if c.driver == v {
+		return
+	}
+	if c.driver == nil {
+		c.driver = v
+		c.notify()
+		return
+	}
+	for _, q := range c.queue {
+		if q == v {
+			return
+		}
+	}
+	c.queue = append(c.queue, v)
+	c.notify()
+}
+
+// HandOver passes the control to the next waiting session, or to the next connected one if nobody is waiting.
+// Only the current driver can hand over the control.
+func (c *controller) HandOver(v *viewer) {
+	c.Lock()
+	defer c.Unlock()
+	if c.driver != v {
+		return
+	}
+	next := c.next()
+	if next == nil {
+		return
+	}
+	c.driver = next
+	c.notify()
+}
+
+// IsDriver returns true if the viewer currently controls the flipper.
+func (c *controller) IsDriver(v *viewer) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.driver == v
+}
+
+// next pops the next driver from the queue. If the queue is empty,
+// the viewer that connected after the current driver is returned.
+// The caller must hold the lock.
+func (c *controller) next() *viewer {
+	if len(c.queue) > 0 {
+		v := c.queue[0]
+		c.queue = c.queue[1:]
+		return v
+	}
+	for i, v := range c.viewers {
+		if v == c.driver {
+			continue
+		}
+		// prefer the viewer after the current driver
+		if i > indexOfViewer(c.viewers, c.driver) {
+			return v
+		}
+	}
+	for _, v := range c.viewers {
+		if v != c.driver {
+			return v
+		}
+	}
+	return nil
+}
+
+// notify sends the current state to all viewers.
+// The caller must hold the lock.
+func (c *controller) notify() {
+	var driver string
+	if c.driver != nil {
+		driver = c.driver.name
+	}
+	for _, v := range c.viewers {
+		msg := controlMsg{
+			driver:   driver,
+			isDriver: v == c.driver,
+			waiting:  len(c.queue),
+			viewers:  len(c.viewers),
+		}
+		for _, q := range c.queue {
+			if q == v {
+				msg.requested = true
+			}
+		}
+		// only the latest state matters, drop the stale one
+		select {
+		case <-v.updates:
+		default:
+		}
+		v.updates <- msg
+	}
+}
+
+// listenControl listens for control state changes and returns them as tea.Cmds.
+// If the viewer left, it stops listening.
+func listenControl(v *viewer) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-v.updates
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func removeViewer(viewers []*viewer, v *viewer) []*viewer {
+	i := indexOfViewer(viewers, v)
+	if i < 0 {
+		return viewers
+	}
+	return append(viewers[:i], viewers[i+1:]...)
+}
+
+func indexOfViewer(viewers []*viewer, v *viewer) int {
+	for i, x := range viewers {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
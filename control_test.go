@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// latest returns the last control state sent to a viewer.
+func latest(t *testing.T, v *viewer) controlMsg {
+	t.Helper()
+	select {
+	case msg := <-v.updates:
+		return msg
+	default:
+		t.Fatalf("%s: no control state received", v.name)
+		return controlMsg{}
+	}
+}
+
+func TestControllerFirstViewerDrives(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+
+	if !c.IsDriver(a) || c.IsDriver(b) {
+		t.Fatal("expected the first viewer to drive")
+	}
+	if msg := latest(t, b); msg.driver != "a" || msg.isDriver || msg.viewers != 2 {
+		t.Fatalf("unexpected state for b: %+v", msg)
+	}
+}
+
+func TestControllerHandOverToWaiting(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+	d := c.Join("d")
+
+	c.Request(d)
+	if msg := latest(t, d); !msg.requested || msg.waiting != 1 {
+		t.Fatalf("expected d to wait for control: %+v", msg)
+	}
+	// only the driver can hand over
+	c.HandOver(b)
+	if !c.IsDriver(a) {
+		t.Fatal("a spectator handed over the control")
+	}
+	c.HandOver(a)
+	if !c.IsDriver(d) {
+		t.Fatal("expected the waiting viewer to get control")
+	}
+	if msg := latest(t, d); !msg.isDriver || msg.requested || msg.waiting != 0 {
+		t.Fatalf("unexpected state for d: %+v", msg)
+	}
+}
+
+func TestControllerHandOverToNext(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+	d := c.Join("d")
+
+	c.HandOver(a)
+	if !c.IsDriver(b) {
+		t.Fatal("expected the viewer after the driver to get control")
+	}
+	c.HandOver(b)
+	if !c.IsDriver(d) {
+		t.Fatal("expected the viewer after the driver to get control")
+	}
+	// wraps around to the first viewer
+	c.HandOver(d)
+	if !c.IsDriver(a) {
+		t.Fatal("expected the control to wrap around")
+	}
+}
+
+func TestControllerHandOverAlone(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	c.HandOver(a)
+	if !c.IsDriver(a) {
+		t.Fatal("expected the only viewer to keep control")
+	}
+}
+
+func TestControllerRequestWithoutDriver(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+	c.Leave(a)
+	if !c.IsDriver(b) {
+		t.Fatal("expected the remaining viewer to get control")
+	}
+	c.Leave(b)
+
+	d := c.Join("d")
+	if !c.IsDriver(d) {
+		t.Fatal("expected a new viewer to drive if nobody is connected")
+	}
+}
+
+func TestControllerLeavePassesControl(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+	d := c.Join("d")
+
+	c.Request(d)
+	c.Leave(a)
+	if !c.IsDriver(d) {
+		t.Fatal("expected the waiting viewer to get control when the driver leaves")
+	}
+	if msg := latest(t, b); msg.driver != "d" || msg.viewers != 2 {
+		t.Fatalf("unexpected state for b: %+v", msg)
+	}
+}
+
+func TestControllerLeaveStopsListening(t *testing.T) {
+	c := newController()
+	a := c.Join("a")
+	b := c.Join("b")
+	c.Leave(b)
+	// leaving twice must not panic
+	c.Leave(b)
+
+	// a state sent before leaving may still be delivered, then listening stops
+	done := make(chan struct{})
+	go func() {
+		for listenControl(b)() != nil {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listenControl is still blocked after leaving")
+	}
+
+	if msg := latest(t, a); msg.viewers != 1 {
+		t.Fatalf("unexpected state for a: %+v", msg)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jon4hz/fztea/flipperui"
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+const (
+	// tileWidth and tileHeight are the size of a tile including its border and label.
+	tileWidth  = 128 + 2
+	tileHeight = 32 + 3
+)
+
+// tileStyle returns the style of a tile, the border of the focused tile is highlighted.
+func tileStyle(theme flipperui.Theme, focused bool) lipgloss.Style {
+	border := theme.Frame
+	if focused {
+		border = theme.Accent
+	}
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(border))
+}
+
+var dashboardCmd = &coral.Command{
+	Use:   "dashboard [port|name]...",
+	Short: "Show multiple flippers at once (default: all attached flippers)",
+	Run:   dashboard,
+}
+
+func init() {
+	addFleetFlags(dashboardCmd)
+}
+
+func dashboard(cmd *coral.Command, args []string) {
+	screenshotResolution, err := parseScreenshotResolution()
+	if err != nil {
+		log.Fatalf("failed to parse screenshot resolution: %s", err)
+	}
+
+	targets, err := fleetTargets(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	themes, err := themeOpts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m := dashboardModel{ctx: cmd.Context()}
+	for _, target := range targets {
+		screens := flipperui.NewBroadcaster()
+		fz, err := recfz.NewFlipperZero(
+			append(target.opts,
+				recfz.WithContext(cmd.Context()),
+				recfz.WithReconnectPolicy(reconnectPolicy()),
+				recfz.WithWaitForDevice(time.Second),
+				recfz.WithStreamScreenCallback(screens.Callback()),
+				recfz.WithLogger(log.New(io.Discard, "", 0)),
+			)...,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fz.Close()
+		if err := fz.Connect(); err != nil {
+			log.Fatal(err)
+		}
+
+		sub := screens.Subscribe()
+		defer sub.Close()
+		states := fz.Subscribe()
+		defer states.Close()
+		labelStates := fz.Subscribe()
+		defer labelStates.Close()
+
+		m.tiles = append(m.tiles, tile{
+			name:     target.name,
+			fz:       fz,
+			selected: true,
+			flipper: flipperui.New(fz, sub.Updates(),
+				append(slices.Clone(themes),
+					flipperui.WithScreenshotResolution(screenshotResolution.width, screenshotResolution.height),
+					flipperui.WithStateUpdates(states.Events()),
+				)...,
+			),
+			states: labelStates.Events(),
+		})
+	}
+
+	if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// tile is a single flipper on the dashboard.
+type tile struct {
+	name    string
+	fz      *recfz.FlipperZero
+	flipper tea.Model
+	states  <-chan recfz.Event
+	state   recfz.Event
+	// selected tiles receive the input in broadcast mode.
+	selected bool
+	// result is the outcome of the last broadcast input.
+	result string
+}
+
+// tileStateMsg is sent when the connection state of a tile changes.
+type tileStateMsg struct {
+	tile  int
+	event recfz.Event
+}
+
+// listenTileState listens for connection state changes of a tile and returns them as tea.Cmds.
+func listenTileState(i int, u <-chan recfz.Event) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-u
+		if !ok {
+			return nil
+		}
+		return tileStateMsg{tile: i, event: e}
+	}
+}
+
+// broadcastMsg is sent when an input was sent to all selected tiles.
+type broadcastMsg struct {
+	key     string
+	tiles   []int
+	results []recfz.Result
+}
+
+// dashboardModel shows multiple flippers in a grid.
+// Only the focused flipper receives input, unless broadcast mode is enabled.
+type dashboardModel struct {
+	ctx           context.Context
+	tiles         []tile
+	focus         int
+	zoom          bool
+	width, height int
+	// broadcast sends the input to all selected tiles.
+	broadcast bool
+	// busy is set while a broadcast input is sent. Further input is dropped meanwhile.
+	busy   bool
+	status string
+	// theme is the theme of the frames and the help, it follows the tile whose theme was cycled last
+	theme flipperui.Theme
+}
+
+// Init is the bubbletea init function.
+func (m dashboardModel) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	for i, t := range m.tiles {
+		cmds = append(cmds, t.flipper.Init(), listenTileState(i, t.states))
+	}
+	return tea.Batch(cmds...)
+}
+
+// Update is the bubbletea update function and handles all tea.Msgs.
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "tab":
+			m.focus = (m.focus + 1) % len(m.tiles)
+			return m, m.resize()
+		case "shift+tab":
+			m.focus = (m.focus - 1 + len(m.tiles)) % len(m.tiles)
+			return m, m.resize()
+		case "ctrl+f":
+			m.zoom = !m.zoom
+			return m, m.resize()
+		case "ctrl+b":
+			m.broadcast = !m.broadcast
+			m.status = ""
+			return m, nil
+		case "ctrl+x":
+			if m.broadcast {
+				m.tiles[m.focus].selected = !m.tiles[m.focus].selected
+				return m, nil
+			}
+		}
+		if m.broadcast {
+			if event, _ := flipperui.ParseKey(msg.String()); event != -1 {
+				return m.sendBroadcast(msg.String())
+			}
+		}
+		return m, m.updateTile(m.focus, msg)
+
+	case tea.MouseMsg:
+		return m, m.updateTile(m.focus, msg)
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, m.resize()
+
+	case flipperui.ThemeMsg:
+		// every tile keeps its own theme and ignores the themes of the others
+		m.theme = msg.Theme
+
+	case tileStateMsg:
+		m.tiles[msg.tile].state = msg.event
+		return m, listenTileState(msg.tile, m.tiles[msg.tile].states)
+
+	case broadcastMsg:
+		m.busy = false
+		var ok int
+		for i, r := range msg.results {
+			m.tiles[msg.tiles[i]].result = formatResult(r)
+			if r.Err == nil {
+				ok++
+			}
+		}
+		m.status = fmt.Sprintf("%s: %d/%d ok", msg.key, ok, len(msg.results))
+		return m, nil
+	}
+
+	// everything else (e.g. screen updates) is passed to all tiles, they pick their own messages
+	cmds := make([]tea.Cmd, 0, len(m.tiles))
+	for i := range m.tiles {
+		cmds = append(cmds, m.updateTile(i, msg))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// sendBroadcast sends a key to all selected tiles in the background.
+// While the key is sent and the screens settle, further input is dropped.
+func (m dashboardModel) sendBroadcast(key string) (tea.Model, tea.Cmd) {
+	if m.busy {
+		return m, nil
+	}
+	var tiles []int
+	var flippers []*recfz.FlipperZero
+	for i, t := range m.tiles {
+		if t.selected {
+			tiles = append(tiles, i)
+			flippers = append(flippers, t.fz)
+		}
+	}
+	if len(flippers) == 0 {
+		m.status = "no flipper selected"
+		return m, nil
+	}
+	m.busy = true
+	m.status = key + ": sending…"
+	fleet := recfz.NewFleet(flippers, fleetOpts()...)
+	return m, func() tea.Msg {
+		results, _ := sendToFleet(m.ctx, fleet, key)
+		return broadcastMsg{key: key, tiles: tiles, results: results}
+	}
+}
+
+// updateTile passes a message to a single tile.
+func (m *dashboardModel) updateTile(i int, msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.tiles[i].flipper, cmd = m.tiles[i].flipper.Update(msg)
+	return cmd
+}
+
+// grid returns the number of columns and rows of the grid.
+func (m dashboardModel) grid() (cols, rows int) {
+	cols = max(1, min(len(m.tiles), m.width/tileWidth))
+	rows = (len(m.tiles) + cols - 1) / cols
+	return cols, rows
+}
+
+// resize tells every tile how much space it has.
+func (m *dashboardModel) resize() tea.Cmd {
+	if m.width == 0 || m.height == 0 {
+		return nil
+	}
+	// one line is reserved for the help
+	height := m.height - 1
+	if m.zoom {
+		return m.updateTile(m.focus, tea.WindowSizeMsg{Width: m.width - 2, Height: height - 3})
+	}
+	cols, rows := m.grid()
+	size := tea.WindowSizeMsg{
+		Width:  m.width/cols - 2,
+		Height: height/rows - 3,
+	}
+	cmds := make([]tea.Cmd, 0, len(m.tiles))
+	for i := range m.tiles {
+		cmds = append(cmds, m.updateTile(i, size))
+	}
+	return tea.Batch(cmds...)
+}
+
+// View is the bubbletea view function.
+func (m dashboardModel) View() string {
+	help := mutedStyle(m.theme).Render("tab/shift+tab: focus • ctrl+f: zoom • ctrl+b: broadcast • ctrl+o: theme • ctrl+c: quit")
+	if m.broadcast {
+		text := "broadcast • ctrl+x: (de)select • tab/shift+tab: focus • ctrl+b: exit broadcast"
+		if m.status != "" {
+			text += " • " + m.status
+		}
+		help = mutedStyle(m.theme).Render(text)
+	}
+	if m.zoom {
+		return lipgloss.JoinVertical(lipgloss.Center,
+			lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, m.tileView(m.focus)),
+			help,
+		)
+	}
+
+	cols, _ := m.grid()
+	var rows []string
+	for i := 0; i < len(m.tiles); i += cols {
+		var row []string
+		for j := i; j < min(i+cols, len(m.tiles)); j++ {
+			row = append(row, m.tileView(j))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, lipgloss.JoinVertical(lipgloss.Left, rows...)),
+		help,
+	)
+}
+
+// tileView renders a single tile with its label.
+func (m dashboardModel) tileView(i int) string {
+	t := m.tiles[i]
+	name := t.name
+	if n := t.state.Info["hardware_name"]; n != "" {
+		name = n
+	}
+	text := fmt.Sprintf("%s • %s • %s", name, t.fz.Port(), t.state.State)
+	if m.broadcast {
+		mark := "○ "
+		if t.selected {
+			mark = "● "
+		}
+		text = mark + text
+		if t.result != "" {
+			text += " • " + t.result
+		}
+	}
+	view := t.flipper.View()
+	label := mutedStyle(m.theme).
+		MaxWidth(max(lipgloss.Width(view), 1)).
+		Render(text)
+	return tileStyle(m.theme, i == m.focus).Render(lipgloss.JoinVertical(lipgloss.Left, label, view))
+}
@@ -0,0 +1,178 @@
+package fakefz
+
+import (
+	"math"
+	"path"
+
+	"github.com/flipperdevices/go-flipper"
+)
+
+const (
+	// menuRows is the number of visible menu entries.
+	menuRows = 5
+	// rowHeight is the height of a single menu entry in pixels.
+	rowHeight = 10
+	// menuTop is the y coordinate of the first menu entry.
+	menuTop = 12
+	// menuWidth is the width of the menu in pixels, the rest of the screen is used for the animation.
+	menuWidth = 76
+
+	// idleTicks is the number of ticks without input after which the demo script takes over.
+	idleTicks = 75
+	// scriptTicks is the number of ticks between two steps of the demo script.
+	scriptTicks = 12
+)
+
+// menuItems are the entries of the main menu of the demo app.
+var menuItems = []string{
+	"Sub-GHz", "125 kHz RFID", "NFC", "Infrared", "GPIO", "iButton", "Bad USB", "U2F", "Apps", "Settings",
+}
+
+// demoScript is the sequence of keys that is played when nobody touched the device for a while.
+var demoScript = []flipper.InputKey{
+	flipper.InputKeyDown, flipper.InputKeyDown, flipper.InputKeyDown, flipper.InputKeyOk,
+	-1, -1, flipper.InputKeyBack, flipper.InputKeyDown, flipper.InputKeyDown, flipper.InputKeyOk,
+	-1, -1, flipper.InputKeyBack, flipper.InputKeyUp, flipper.InputKeyUp, flipper.InputKeyUp,
+	flipper.InputKeyUp, flipper.InputKeyUp,
+}
+
+// demoApp is a small scripted app rendering a menu and an animation.
+type demoApp struct {
+	ticks    int
+	idle     int
+	step     int
+	selected int
+	offset   int
+	open     bool
+	// title and args of the open app
+	title string
+	args  string
+	// position and velocity of the bouncing ball
+	ballX, ballY   float64
+	ballVX, ballVY float64
+}
+
+func newDemoApp() *demoApp {
+	return &demoApp{
+		ballX:  menuWidth + 10,
+		ballY:  20,
+		ballVX: 1.3,
+		ballVY: 0.9,
+	}
+}
+
+// tick advances the animation by one frame and plays the demo script if the app is idle.
+func (a *demoApp) tick() {
+	a.ticks++
+	a.idle++
+
+	a.ballX += a.ballVX
+	a.ballY += a.ballVY
+	if a.ballX < menuWidth+2 || a.ballX > screenWidth-6 {
+		a.ballVX = -a.ballVX
+	}
+	if a.ballY < menuTop || a.ballY > screenHeight-6 {
+		a.ballVY = -a.ballVY
+	}
+
+	if a.idle >= idleTicks && a.idle%scriptTicks == 0 {
+		key := demoScript[a.step%len(demoScript)]
+		a.step++
+		if key != -1 {
+			a.press(key)
+		}
+	}
+}
+
+// input handles an input event sent over rpc.
+func (a *demoApp) input(key flipper.InputKey, typ flipper.InputType) {
+	if typ != flipper.InputTypeShort && typ != flipper.InputTypeLong {
+		return
+	}
+	a.idle = 0
+	a.step = 0
+	a.press(key)
+}
+
+// press handles a key press.
+func (a *demoApp) press(key flipper.InputKey) {
+	if a.open {
+		if key == flipper.InputKeyBack {
+			a.open = false
+		}
+		return
+	}
+	switch key {
+	case flipper.InputKeyUp:
+		a.selected = (a.selected - 1 + len(menuItems)) % len(menuItems)
+	case flipper.InputKeyDown:
+		a.selected = (a.selected + 1) % len(menuItems)
+	case flipper.InputKeyOk, flipper.InputKeyRight:
+		a.launch(menuItems[a.selected], "")
+	}
+	// keep the selection visible
+	if a.selected < a.offset {
+		a.offset = a.selected
+	}
+	if a.selected >= a.offset+menuRows {
+		a.offset = a.selected - menuRows + 1
+	}
+}
+
+// launch opens an app.
+func (a *demoApp) launch(title, args string) {
+	a.open = true
+	a.title = title
+	a.args = args
+}
+
+// render draws the current state of the app.
+func (a *demoApp) render() []byte {
+	var c canvas
+	// title bar
+	c.fill(0, 0, screenWidth, 9, true)
+	c.text(2, 2, "fztea demo", false)
+	c.text(screenWidth-textWidth("12:00")-1, 2, "12:00", false)
+
+	if a.open {
+		a.renderApp(&c)
+	} else {
+		a.renderMenu(&c)
+	}
+	return c[:]
+}
+
+// renderMenu draws the main menu and the bouncing ball next to it.
+func (a *demoApp) renderMenu(c *canvas) {
+	for i := 0; i < menuRows && a.offset+i < len(menuItems); i++ {
+		idx := a.offset + i
+		y := menuTop + i*rowHeight
+		if idx == a.selected {
+			c.fill(0, y-2, menuWidth-2, rowHeight-1, true)
+			c.text(3, y, menuItems[idx], false)
+			continue
+		}
+		c.text(3, y, menuItems[idx], true)
+	}
+	// scrollbar
+	barHeight := (screenHeight - menuTop) * menuRows / len(menuItems)
+	barTop := menuTop + (screenHeight-menuTop-barHeight)*a.offset/(len(menuItems)-menuRows)
+	c.fill(menuWidth-1, barTop-2, 1, barHeight, true)
+
+	c.frame(menuWidth+1, menuTop-2, screenWidth-menuWidth-1, screenHeight-menuTop+2)
+	c.fill(int(a.ballX), int(a.ballY), 4, 4, true)
+}
+
+// renderApp draws the open app with an animated sine wave.
+func (a *demoApp) renderApp(c *canvas) {
+	c.text((screenWidth-textWidth(a.title))/2, 14, a.title, true)
+	if a.args != "" {
+		name := path.Base(a.args)
+		c.text((screenWidth-textWidth(name))/2, 22, name, true)
+	}
+	for x := 0; x < screenWidth; x++ {
+		y := 38 + int(10*math.Sin(float64(x+a.ticks*2)/10))
+		c.set(x, y, true)
+	}
+	c.text((screenWidth-textWidth("press back"))/2, screenHeight-7, "press back", true)
+}
@@ -0,0 +1,518 @@
+// Package fakefz emulates a flipper zero device.
+// It speaks enough of the cli and the rpc protocol to be used with recfz
+// for offline development, demos and tests.
+package fakefz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const (
+	screenWidth  = 128
+	screenHeight = 64
+
+	// defaultFrameInterval is the time between two frames of the animation.
+	defaultFrameInterval = time.Second / 15
+
+	// storageSize is the size of the simulated sd card.
+	storageSize = 64 << 20
+)
+
+// ErrOffline is returned when dialing a device that is offline.
+var ErrOffline = errors.New("fake flipper is offline")
+
+// Opts represents an optional configuration for the fake device.
+type Opts func(d *Device)
+
+// WithName sets the name of the fake device.
+func WithName(name string) Opts {
+	return func(d *Device) {
+		d.name = name
+	}
+}
+
+// WithFile adds a file to the storage of the fake device.
+// Missing parent directories are created automatically.
+func WithFile(p string, data []byte) Opts {
+	return func(d *Device) {
+		d.storage.writeFile(p, data)
+	}
+}
+
+// WithFrameInterval sets the time between two frames of the animation.
+func WithFrameInterval(interval time.Duration) Opts {
+	return func(d *Device) {
+		d.frameInterval = interval
+	}
+}
+
+// WithBattery sets the charge level in percent and if the battery of the fake device is charging.
+func WithBattery(charge int, charging bool) Opts {
+	return func(d *Device) {
+		d.charge = charge
+		d.charging = charging
+	}
+}
+
+// Device is a fake flipper zero.
+type Device struct {
+	mu            sync.Mutex
+	name          string
+	storage       *storage
+	app           *demoApp
+	sessions      map[*session]struct{}
+	offline       bool
+	frameInterval time.Duration
+	charge        int
+	charging      bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// NewDevice creates a new fake device and starts its animation.
+func NewDevice(opts ...Opts) *Device {
+	d := &Device{
+		name:          "Fztea",
+		storage:       newStorage(),
+		app:           newDemoApp(),
+		sessions:      make(map[*session]struct{}),
+		frameInterval: defaultFrameInterval,
+		charge:        87,
+	}
+	d.storage.populate()
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	go d.animate()
+	return d
+}
+
+// Name returns the name of the device.
+func (d *Device) Name() string {
+	return d.name
+}
+
+// Transport returns a recfz transport connecting to the device in memory.
+func (d *Device) Transport() recfz.Transport {
+	return &transport{d: d}
+}
+
+// Disconnect closes all open connections, as if the device was unplugged and plugged in again.
+func (d *Device) Disconnect() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for s := range d.sessions {
+		s.close()
+	}
+}
+
+// SetOffline simulates an unplugged device. While offline, all connections are closed and dialing fails.
+func (d *Device) SetOffline(offline bool) {
+	d.mu.Lock()
+	d.offline = offline
+	d.mu.Unlock()
+	if offline {
+		d.Disconnect()
+	}
+}
+
+// Close stops the device and closes all connections.
+func (d *Device) Close() {
+	d.cancel()
+	d.Disconnect()
+}
+
+// dial opens a new in-memory connection and serves it.
+func (d *Device) dial() (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.offline {
+		return nil, ErrOffline
+	}
+	if d.ctx.Err() != nil {
+		return nil, d.ctx.Err()
+	}
+	client, server := net.Pipe()
+	s := newSession(d, server)
+	d.sessions[s] = struct{}{}
+	go func() {
+		s.serve()
+		d.mu.Lock()
+		delete(d.sessions, s)
+		d.mu.Unlock()
+	}()
+	return client, nil
+}
+
+// animate renders the demo app and sends the frames to all sessions streaming the screen.
+func (d *Device) animate() {
+	ticker := time.NewTicker(d.frameInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.app.tick()
+			frame := d.app.render()
+			for s := range d.sessions {
+				s.sendFrame(frame)
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// input passes an input event to the demo app.
+func (d *Device) input(key flipper.InputKey, typ flipper.InputType) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.app.input(key, typ)
+}
+
+// startApp starts an app of the demo app by its menu entry or the path of an installed .fap file.
+func (d *Device) startApp(name, args string) uint64 {
+	if strings.EqualFold(path.Ext(name), ".fap") {
+		if n, ok := d.storage.get(name); !ok || n.dir {
+			return statusErrorAppCantStart
+		}
+		name = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	} else if !slices.Contains(menuItems, name) {
+		return statusErrorAppCantStart
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.app.open {
+		return statusErrorAppLocked
+	}
+	d.app.launch(name, args)
+	return statusOK
+}
+
+// appLocked returns true if an app of the demo app is open.
+func (d *Device) appLocked() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.app.open
+}
+
+// deviceInfo returns the device information reported over rpc.
+func (d *Device) deviceInfo() [][2]string {
+	return [][2]string{
+		{"device_info_major", "2"},
+		{"device_info_minor", "0"},
+		{"hardware_model", "Flipper Zero"},
+		{"hardware_uid", "0011223344556677"},
+		{"hardware_name", d.name},
+		{"firmware_commit", "fztea"},
+		{"firmware_branch", "fake"},
+		{"firmware_version", "0.0.0"},
+		{"firmware_build_date", "17-10-2026"},
+		{"protobuf_version_major", "0"},
+		{"protobuf_version_minor", "6"},
+	}
+}
+
+// powerInfo returns the battery status reported over rpc.
+func (d *Device) powerInfo() [][2]string {
+	state, voltage := "discharging", 3300+d.charge*9
+	if d.charging {
+		state, voltage = "charging", voltage+150
+	}
+	return [][2]string{
+		{"format_major", "2"},
+		{"format_minor", "0"},
+		{"charge_level", strconv.Itoa(d.charge)},
+		{"charge_state", state},
+		{"battery_voltage", strconv.Itoa(voltage)},
+		{"battery_current", "-12"},
+		{"battery_temp", "26"},
+		{"battery_health", "100"},
+		{"capacity_remain", strconv.Itoa(2100 * d.charge / 100)},
+		{"capacity_full", "2100"},
+	}
+}
+
+// storage is a very simple in-memory file system.
+type storage struct {
+	mu    sync.Mutex
+	nodes map[string]*node
+}
+
+// node is a file or directory in the storage.
+type node struct {
+	dir  bool
+	data []byte
+}
+
+func newStorage() *storage {
+	return &storage{
+		nodes: map[string]*node{
+			"/":    {dir: true},
+			"/ext": {dir: true},
+			"/int": {dir: true},
+		},
+	}
+}
+
+// populate adds some example files to the storage.
+func (s *storage) populate() {
+	s.writeFile("/ext/subghz/gate.sub", []byte("Filetype: Flipper SubGhz Key File\nVersion: 1\nFrequency: 433920000\nPreset: FuriHalSubGhzPresetOok650Async\nProtocol: Princeton\nBit: 24\nKey: 00 00 00 00 00 95 D5 D4\nTE: 400\n"))
+	s.writeFile("/ext/infrared/tv.ir", []byte("Filetype: IR signals file\nVersion: 1\n#\nname: Power\ntype: parsed\nprotocol: NEC\naddress: 04 00 00 00\ncommand: 08 00 00 00\n"))
+	s.writeFile("/ext/nfc/card.nfc", []byte("Filetype: Flipper NFC device\nVersion: 3\nDevice type: UID\nUID: 04 6E 2B 3A 5C 12 90\n"))
+	s.writeFile("/ext/badusb/hello.txt", []byte("REM says hello\nSTRING Hello from fztea\nENTER\n"))
+	s.writeFile("/ext/apps/Games/snake_game.fap", []byte("Fap\x01fake snake game"))
+	s.writeFile("/ext/apps/Tools/clock.fap", []byte("Fap\x01fake clock"))
+}
+
+// writeFile creates or replaces a file and creates missing parent directories.
+func (s *storage) writeFile(p string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = path.Clean(p)
+	s.mkdirAllLocked(path.Dir(p))
+	s.nodes[p] = &node{data: data}
+}
+
+// mkdirAll creates a directory and all missing parents.
+func (s *storage) mkdirAll(p string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mkdirAllLocked(path.Clean(p))
+}
+
+func (s *storage) mkdirAllLocked(p string) {
+	for ; p != "/" && p != "."; p = path.Dir(p) {
+		if _, ok := s.nodes[p]; ok {
+			return
+		}
+		s.nodes[p] = &node{dir: true}
+	}
+}
+
+// get returns the node at the given path.
+func (s *storage) get(p string) (*node, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[path.Clean(p)]
+	return n, ok
+}
+
+// isRoot returns true for the directories that can't be modified.
+func isRoot(p string) bool {
+	return p == "/" || p == "/ext" || p == "/int"
+}
+
+// writable returns the status of writing a file to the given path.
+// The parent directory must exist and the path must not be a directory.
+func (s *storage) writable(p string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = path.Clean(p)
+	if n, ok := s.nodes[p]; ok && n.dir {
+		return statusErrorStorageInvalid
+	}
+	if n, ok := s.nodes[path.Dir(p)]; !ok || !n.dir || path.Dir(p) == "/" {
+		return statusErrorStorageNotExist
+	}
+	return statusOK
+}
+
+// mkdir creates a single directory.
+func (s *storage) mkdir(p string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = path.Clean(p)
+	if _, ok := s.nodes[p]; ok {
+		return statusErrorStorageExist
+	}
+	if n, ok := s.nodes[path.Dir(p)]; !ok || !n.dir || path.Dir(p) == "/" {
+		return statusErrorStorageNotExist
+	}
+	s.nodes[p] = &node{dir: true}
+	return statusOK
+}
+
+// remove removes a file or directory. Non-empty directories are only removed if recursive is set.
+// Like the real flipper, removing a path that doesn't exist succeeds.
+func (s *storage) remove(p string, recursive bool) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = path.Clean(p)
+	if isRoot(p) {
+		return statusErrorStorageDenied
+	}
+	if _, ok := s.nodes[p]; !ok {
+		return statusOK
+	}
+	children := s.childrenLocked(p)
+	if len(children) > 0 && !recursive {
+		return statusErrorDirNotEmpty
+	}
+	for _, c := range children {
+		delete(s.nodes, c)
+	}
+	delete(s.nodes, p)
+	return statusOK
+}
+
+// rename moves a file or directory including its children. The target must not exist.
+func (s *storage) rename(oldPath, newPath string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	oldPath, newPath = path.Clean(oldPath), path.Clean(newPath)
+	if isRoot(oldPath) || isRoot(newPath) {
+		return statusErrorStorageDenied
+	}
+	n, ok := s.nodes[oldPath]
+	if !ok {
+		return statusErrorStorageNotExist
+	}
+	if _, ok := s.nodes[newPath]; ok {
+		return statusErrorStorageExist
+	}
+	if parent, ok := s.nodes[path.Dir(newPath)]; !ok || !parent.dir {
+		return statusErrorStorageNotExist
+	}
+	if strings.HasPrefix(newPath, oldPath+"/") {
+		return statusErrorStorageInvalid
+	}
+	for _, c := range s.childrenLocked(oldPath) {
+		s.nodes[newPath+strings.TrimPrefix(c, oldPath)] = s.nodes[c]
+		delete(s.nodes, c)
+	}
+	delete(s.nodes, oldPath)
+	s.nodes[newPath] = n
+	return statusOK
+}
+
+// childrenLocked returns the paths of all nodes below p.
+func (s *storage) childrenLocked(p string) []string {
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	var children []string
+	for k := range s.nodes {
+		if k != p && strings.HasPrefix(k, prefix) {
+			children = append(children, k)
+		}
+	}
+	return children
+}
+
+// used returns the number of bytes used by all files.
+func (s *storage) used() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var used uint64
+	for _, n := range s.nodes {
+		used += uint64(len(n.data))
+	}
+	return used
+}
+
+// entry is a single entry of a directory listing.
+type entry struct {
+	name string
+	*node
+}
+
+// list returns the entries of a directory sorted by name.
+func (s *storage) list(p string) ([]entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = path.Clean(p)
+	if n, ok := s.nodes[p]; !ok || !n.dir {
+		return nil, false
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	var entries []entry
+	for k, n := range s.nodes {
+		if k == p || !strings.HasPrefix(k, prefix) || strings.Contains(k[len(prefix):], "/") {
+			continue
+		}
+		entries = append(entries, entry{name: k[len(prefix):], node: n})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, true
+}
+
+// transport implements recfz.Transport for the fake device.
+type transport struct {
+	d *Device
+}
+
+// Dial opens a new in-memory connection to the fake device.
+func (t *transport) Dial(_ context.Context) (recfz.Conn, error) {
+	conn, err := t.d.dial()
+	if err != nil {
+		return nil, err
+	}
+	return &pipeConn{Conn: conn}, nil
+}
+
+// Available returns ErrOffline if the fake device is offline.
+func (t *transport) Available() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	if t.d.offline {
+		return ErrOffline
+	}
+	return nil
+}
+
+// String describes the fake device.
+func (t *transport) String() string {
+	return "fake://" + t.d.name
+}
+
+// pipeConn is the client side of an in-memory connection.
+type pipeConn struct {
+	net.Conn
+	mu  sync.Mutex
+	err error
+}
+
+// Read reads from the connection.
+func (c *pipeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.setErr(err)
+	return n, err
+}
+
+// Write writes to the connection.
+func (c *pipeConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.setErr(err)
+	return n, err
+}
+
+// Alive returns the first error that occurred on the connection.
+func (c *pipeConn) Alive() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *pipeConn) setErr(err error) {
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
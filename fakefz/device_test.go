@@ -0,0 +1,191 @@
+package fakefz_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/internal/race"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const testTimeout = 5 * time.Second
+
+// connect connects a flipper zero to the device, both are closed when the test ends.
+func connect(t *testing.T, d *fakefz.Device, opts ...recfz.Opts) *recfz.FlipperZero {
+	t.Helper()
+	race.SkipGoFlipper(t)
+	t.Cleanup(d.Close)
+	opts = append([]recfz.Opts{recfz.WithTransport(d.Transport()), recfz.WithLogger(log.New(io.Discard, "", 0))}, opts...)
+	f, err := recfz.NewFlipperZero(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(f.Close)
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestCLI(t *testing.T) {
+	d := fakefz.NewDevice()
+	defer d.Close()
+	conn, err := d.Transport().Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	readPrompt := func() string {
+		t.Helper()
+		var out strings.Builder
+		for !strings.HasSuffix(out.String(), ">: ") {
+			b, err := br.ReadByte()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.WriteByte(b)
+		}
+		return out.String()
+	}
+	if banner := readPrompt(); !strings.Contains(banner, "Flipper Zero Command Line Interface") {
+		t.Fatalf("unexpected banner %q", banner)
+	}
+	if _, err := conn.Write([]byte("help\r")); err != nil {
+		t.Fatal(err)
+	}
+	if out := readPrompt(); !strings.Contains(out, "command not found") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := conn.Write([]byte("start_rpc_session\r")); err != nil {
+		t.Fatal(err)
+	}
+	if echo, err := br.ReadString('\r'); err != nil || echo != "start_rpc_session\r" {
+		t.Fatalf("unexpected echo %q: %v", echo, err)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	d := fakefz.NewDevice()
+	defer d.Close()
+	tr := d.Transport()
+	conn, err := tr.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d.SetOffline(true)
+	// reads the banner until the connection is closed
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Alive() == nil {
+		t.Fatal("expected the connection to be dead")
+	}
+	if _, err := tr.Dial(context.Background()); !errors.Is(err, fakefz.ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+	if err := tr.(recfz.Detector).Available(); !errors.Is(err, fakefz.ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+
+	d.SetOffline(false)
+	conn, err = tr.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestScreenStream(t *testing.T) {
+	frames := make(chan []byte, 1)
+	connect(t, fakefz.NewDevice(fakefz.WithFrameInterval(10*time.Millisecond)),
+		recfz.WithStreamScreenCallback(func(frame flipper.ScreenFrame) {
+			select {
+			case frames <- bytes.Clone(frame.Bytes()):
+			default:
+			}
+		}))
+
+	select {
+	case frame := <-frames:
+		if len(frame) != 128*64/8 {
+			t.Fatalf("unexpected frame size %d", len(frame))
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no screen frame received")
+	}
+}
+
+func TestApps(t *testing.T) {
+	f := connect(t, fakefz.NewDevice())
+	ctx := testContext(t)
+
+	if err := f.StartApp(ctx, "Doom", ""); !errors.Is(err, recfz.ErrAppCantStart) {
+		t.Fatalf("expected ErrAppCantStart, got %v", err)
+	}
+	if err := f.StartApp(ctx, "Sub-GHz", ""); err != nil {
+		t.Fatal(err)
+	}
+	if locked, err := f.AppLocked(ctx); err != nil || !locked {
+		t.Fatalf("expected the app to run: %v", err)
+	}
+	if err := f.StartApp(ctx, "/ext/apps/Tools/clock.fap", ""); !errors.Is(err, recfz.ErrAppLocked) {
+		t.Fatalf("expected ErrAppLocked, got %v", err)
+	}
+
+	// back closes the app
+	if err := f.SendShortPress(flipper.InputKeyBack); err != nil {
+		t.Fatal(err)
+	}
+	if locked, err := f.AppLocked(ctx); err != nil || locked {
+		t.Fatalf("expected the app to be closed: %v", err)
+	}
+	if err := f.StartApp(ctx, "/ext/apps/Tools/clock.fap", ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	// larger than a single storage message
+	data := bytes.Repeat([]byte("fztea"), 300)
+	f := connect(t, fakefz.NewDevice(fakefz.WithFile("/ext/test/data.txt", data)))
+	ctx := testContext(t)
+
+	entries, err := f.List(ctx, "/ext/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "data.txt" || entries[0].Size() != int64(len(data)) {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.ReadFile(ctx, "/ext/test/data.txt", &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("read data differs")
+	}
+	if _, err := f.List(ctx, "/ext/missing"); err == nil {
+		t.Fatal("expected an error listing a missing directory")
+	}
+}
@@ -0,0 +1,85 @@
+package fakefz
+
+import "strings"
+
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+	// glyphAdvance is the horizontal space a single character takes, including the gap.
+	glyphAdvance = glyphWidth + 1
+)
+
+// font is a tiny 3x5 pixel font. Each glyph consists of five rows with three pixels each.
+var font = map[rune]string{
+	'A': "010101111101101", 'B': "110101110101110", 'C': "011100100100011", 'D': "110101101101110",
+	'E': "111100110100111", 'F': "111100110100100", 'G': "011100101101011", 'H': "101101111101101",
+	'I': "111010010010111", 'J': "001001001101010", 'K': "101101110101101", 'L': "100100100100111",
+	'M': "101111111101101", 'N': "110101101101101", 'O': "010101101101010", 'P': "110101110100100",
+	'Q': "010101101110011", 'R': "110101110101101", 'S': "011100010001110", 'T': "111010010010010",
+	'U': "101101101101111", 'V': "101101101101010", 'W': "101101111111101", 'X': "101101010101101",
+	'Y': "101101010010010", 'Z': "111001010100111",
+	'0': "111101101101111", '1': "010110010010111", '2': "110001010100111", '3': "110001010001110",
+	'4': "101101111001001", '5': "111100110001110", '6': "011100111101111", '7': "111001010010010",
+	'8': "111101111101111", '9': "111101111001110",
+	'-': "000000111000000", '.': "000000000000010", ':': "000010000010000", '/': "001001010100100",
+	'>': "100010001010100", '_': "000000000000111", '!': "010010010000010", ' ': "000000000000000",
+}
+
+// canvas is a monochrome frame buffer in the format of the flipper screen.
+// Every byte holds a column of eight pixels.
+type canvas [screenWidth * screenHeight / 8]byte
+
+// set sets or clears a single pixel. Pixels outside of the screen are ignored.
+func (c *canvas) set(x, y int, on bool) {
+	if x < 0 || y < 0 || x >= screenWidth || y >= screenHeight {
+		return
+	}
+	i := (y/8)*screenWidth + x
+	if on {
+		c[i] |= 1 << (y & 7)
+	} else {
+		c[i] &^= 1 << (y & 7)
+	}
+}
+
+// fill sets or clears a rectangle.
+func (c *canvas) fill(x, y, w, h int, on bool) {
+	for dx := 0; dx < w; dx++ {
+		for dy := 0; dy < h; dy++ {
+			c.set(x+dx, y+dy, on)
+		}
+	}
+}
+
+// frame draws the outline of a rectangle.
+func (c *canvas) frame(x, y, w, h int) {
+	for dx := 0; dx < w; dx++ {
+		c.set(x+dx, y, true)
+		c.set(x+dx, y+h-1, true)
+	}
+	for dy := 0; dy < h; dy++ {
+		c.set(x, y+dy, true)
+		c.set(x+w-1, y+dy, true)
+	}
+}
+
+// text draws a string with the top left corner at x, y.
+// Unknown characters are drawn as spaces. If on is false, the text is drawn inverted.
+func (c *canvas) text(x, y int, s string, on bool) {
+	for _, r := range strings.ToUpper(s) {
+		glyph, ok := font[r]
+		if ok {
+			for i, p := range glyph {
+				if p == '1' {
+					c.set(x+i%glyphWidth, y+i/glyphWidth, on)
+				}
+			}
+		}
+		x += glyphAdvance
+	}
+}
+
+// textWidth returns the width of a string in pixels.
+func textWidth(s string) int {
+	return len([]rune(s)) * glyphAdvance
+}
@@ -0,0 +1,209 @@
+package fakefz
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field numbers of the PB.Main message and its content.
+// The generated protobuf code of go-flipper is internal, so the messages are encoded by hand.
+const (
+	fieldCommandID     protowire.Number = 1
+	fieldCommandStatus protowire.Number = 2
+	fieldHasNext       protowire.Number = 3
+
+	fieldEmpty                       protowire.Number = 4
+	fieldSystemPingRequest           protowire.Number = 5
+	fieldSystemPingResponse          protowire.Number = 6
+	fieldStorageListRequest          protowire.Number = 7
+	fieldStorageListResponse         protowire.Number = 8
+	fieldStorageReadRequest          protowire.Number = 9
+	fieldStorageReadResponse         protowire.Number = 10
+	fieldStorageWriteRequest         protowire.Number = 11
+	fieldStorageDeleteRequest        protowire.Number = 12
+	fieldStorageMkdirRequest         protowire.Number = 13
+	fieldStorageMd5SumRequest        protowire.Number = 14
+	fieldStorageMd5SumResponse       protowire.Number = 15
+	fieldAppStartRequest             protowire.Number = 16
+	fieldAppLockStatusRequest        protowire.Number = 17
+	fieldAppLockStatusResponse       protowire.Number = 18
+	fieldStopSession                 protowire.Number = 19
+	fieldGuiStartScreenStreamRequest protowire.Number = 20
+	fieldGuiStopScreenStreamRequest  protowire.Number = 21
+	fieldGuiScreenFrame              protowire.Number = 22
+	fieldGuiSendInputEventRequest    protowire.Number = 23
+	fieldStorageStatRequest          protowire.Number = 24
+	fieldStorageStatResponse         protowire.Number = 25
+	fieldStorageInfoRequest          protowire.Number = 28
+	fieldStorageInfoResponse         protowire.Number = 29
+	fieldStorageRenameRequest        protowire.Number = 30
+	fieldSystemDeviceInfoRequest     protowire.Number = 32
+	fieldSystemDeviceInfoResponse    protowire.Number = 33
+	fieldSystemPowerInfoRequest      protowire.Number = 44
+	fieldSystemPowerInfoResponse     protowire.Number = 45
+)
+
+// command status codes of the rpc protocol.
+const (
+	statusOK                   uint64 = 0
+	statusError                uint64 = 1
+	statusErrorDecode          uint64 = 2
+	statusErrorNotImplemented  uint64 = 3
+	statusErrorStorageExist    uint64 = 6
+	statusErrorStorageNotExist uint64 = 7
+	statusErrorStorageDenied   uint64 = 9
+	statusErrorStorageInvalid  uint64 = 10
+	statusErrorInvalidParams   uint64 = 15
+	statusErrorAppCantStart    uint64 = 16
+	statusErrorAppLocked       uint64 = 17
+	statusErrorDirNotEmpty     uint64 = 18
+)
+
+// field represents a single decoded protobuf field.
+type field struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// decodeFields decodes all top level fields of a protobuf message.
+// Only varint and length delimited fields are supported, others are skipped.
+func decodeFields(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := field{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// getVarint returns the last value of the varint field with the given number.
+func getVarint(fields []field, num protowire.Number) uint64 {
+	var v uint64
+	for _, f := range fields {
+		if f.num == num {
+			v = f.varint
+		}
+	}
+	return v
+}
+
+// getBytes returns the last value of the length delimited field with the given number.
+func getBytes(fields []field, num protowire.Number) []byte {
+	var v []byte
+	for _, f := range fields {
+		if f.num == num {
+			v = f.bytes
+		}
+	}
+	return v
+}
+
+// request is a decoded PB.Main message sent by the client.
+type request struct {
+	commandID uint32
+	hasNext   bool
+	// content is the field number of the oneof content.
+	content protowire.Number
+	// fields are the decoded fields of the content message.
+	fields []field
+}
+
+// decodeRequest decodes a PB.Main message.
+func decodeRequest(b []byte) (*request, error) {
+	fields, err := decodeFields(b)
+	if err != nil {
+		return nil, err
+	}
+	req := &request{}
+	for _, f := range fields {
+		switch {
+		case f.num == fieldCommandID:
+			req.commandID = uint32(f.varint)
+		case f.num == fieldHasNext:
+			req.hasNext = f.varint != 0
+		case f.num >= fieldEmpty:
+			req.content = f.num
+			req.fields, err = decodeFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.content == 0 {
+		return nil, errors.New("request without content")
+	}
+	return req, nil
+}
+
+// encodeMain encodes a PB.Main message with the given content.
+func encodeMain(commandID uint32, status uint64, hasNext bool, content protowire.Number, msg []byte) []byte {
+	var b []byte
+	if commandID != 0 {
+		b = protowire.AppendTag(b, fieldCommandID, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(commandID))
+	}
+	if status != statusOK {
+		b = protowire.AppendTag(b, fieldCommandStatus, protowire.VarintType)
+		b = protowire.AppendVarint(b, status)
+	}
+	if hasNext {
+		b = protowire.AppendTag(b, fieldHasNext, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	b = protowire.AppendTag(b, content, protowire.BytesType)
+	b = protowire.AppendBytes(b, msg)
+	return b
+}
+
+// appendVarintField appends a varint field, omitting zero values like proto3 does.
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendBytesField appends a length delimited field, omitting empty values like proto3 does.
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// appendMessageField appends an embedded message, even if it is empty.
+func appendMessageField(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// encodeFile encodes a PB_Storage.File message.
+func encodeFile(typ uint64, name string, size uint32, data []byte) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, typ)
+	b = appendBytesField(b, 2, []byte(name))
+	b = appendVarintField(b, 3, uint64(size))
+	b = appendBytesField(b, 4, data)
+	return b
+}
@@ -0,0 +1,357 @@
+package fakefz
+
+import (
+	"bufio"
+	"crypto/md5" //nolint:gosec
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"path"
+	"sync"
+
+	"github.com/flipperdevices/go-flipper"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// cliBanner is printed when a connection is opened, followed by the prompt.
+	cliBanner = "\r\n              _.-------.._                    -,\r\n" +
+		"Welcome to Flipper Zero Command Line Interface!\r\n" +
+		"Read Manual https://docs.flipperzero.one\r\n\r\n>: "
+	startRPCSessionCommand = "start_rpc_session\r"
+
+	// maxPayloadLength is the maximum size of the data in a single storage message.
+	maxPayloadLength = 512
+	// maxListEntries is the maximum number of files in a single list response.
+	maxListEntries = 8
+)
+
+// session is a single connection to the fake device.
+type session struct {
+	d    *Device
+	conn net.Conn
+	// out holds encoded responses in the order they must be sent.
+	out chan []byte
+	// frames holds the latest screen frame, if the screen is streamed.
+	frames    chan []byte
+	mu        sync.Mutex
+	streaming bool
+	closed    chan struct{}
+	closeOnce sync.Once
+	// write is the multi-part write in progress. It's only accessed by serve.
+	write *pendingWrite
+}
+
+// pendingWrite is a write request whose last part hasn't been received yet.
+type pendingWrite struct {
+	commandID uint32
+	path      string
+	data      []byte
+	status    uint64
+}
+
+func newSession(d *Device, conn net.Conn) *session {
+	return &session{
+		d:      d,
+		conn:   conn,
+		out:    make(chan []byte, 64),
+		frames: make(chan []byte, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+// close closes the session and its connection.
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.conn.Close()
+	})
+}
+
+// serve runs the cli until the rpc session is started and then handles rpc requests.
+func (s *session) serve() {
+	defer s.close()
+	br := bufio.NewReader(s.conn)
+	if !s.cli(br) {
+		return
+	}
+	go s.writeLoop()
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return
+		}
+		req, err := decodeRequest(buf)
+		if err != nil {
+			s.respond(0, statusErrorDecode, false, fieldEmpty, nil)
+			continue
+		}
+		if req.content == fieldStopSession {
+			s.respond(req.commandID, statusOK, false, fieldEmpty, nil)
+			return
+		}
+		s.handle(req)
+	}
+}
+
+// cli prints the banner and waits for the rpc session to be started.
+// It returns false if the connection was closed before.
+func (s *session) cli(br *bufio.Reader) bool {
+	if _, err := s.conn.Write([]byte(cliBanner)); err != nil {
+		return false
+	}
+	for {
+		line, err := br.ReadString('\r')
+		if err != nil {
+			return false
+		}
+		if line == startRPCSessionCommand {
+			_, err := s.conn.Write([]byte(startRPCSessionCommand))
+			return err == nil
+		}
+		if _, err := s.conn.Write([]byte("\r\ncommand not found\r\n\r\n>: ")); err != nil {
+			return false
+		}
+	}
+}
+
+// writeLoop writes responses and screen frames to the connection.
+func (s *session) writeLoop() {
+	for {
+		var msg []byte
+		select {
+		case <-s.closed:
+			return
+		case msg = <-s.out:
+		case msg = <-s.frames:
+		}
+		if err := writeDelimited(s.conn, msg); err != nil {
+			s.close()
+			return
+		}
+	}
+}
+
+// writeDelimited writes a varint length prefixed record.
+func writeDelimited(w io.Writer, msg []byte) error {
+	b := protowire.AppendVarint(nil, uint64(len(msg)))
+	_, err := w.Write(append(b, msg...))
+	return err
+}
+
+// respond queues a response for the given command.
+func (s *session) respond(commandID uint32, status uint64, hasNext bool, content protowire.Number, msg []byte) {
+	select {
+	case s.out <- encodeMain(commandID, status, hasNext, content, msg):
+	case <-s.closed:
+	}
+}
+
+// sendFrame queues a screen frame if the screen is streamed.
+// Only the latest frame is kept if the client doesn't keep up.
+func (s *session) sendFrame(frame []byte) {
+	s.mu.Lock()
+	streaming := s.streaming
+	s.mu.Unlock()
+	if !streaming {
+		return
+	}
+	msg := encodeMain(0, statusOK, false, fieldGuiScreenFrame, appendBytesField(nil, 1, frame))
+	select {
+	case <-s.frames:
+	default:
+	}
+	select {
+	case s.frames <- msg:
+	default:
+	}
+}
+
+// handle handles a single rpc request.
+func (s *session) handle(req *request) {
+	switch req.content {
+	case fieldSystemPingRequest:
+		s.respond(req.commandID, statusOK, false, fieldSystemPingResponse, appendBytesField(nil, 1, getBytes(req.fields, 1)))
+
+	case fieldSystemDeviceInfoRequest:
+		info := s.d.deviceInfo()
+		for i, kv := range info {
+			var msg []byte
+			msg = appendBytesField(msg, 1, []byte(kv[0]))
+			msg = appendBytesField(msg, 2, []byte(kv[1]))
+			s.respond(req.commandID, statusOK, i < len(info)-1, fieldSystemDeviceInfoResponse, msg)
+		}
+
+	case fieldSystemPowerInfoRequest:
+		info := s.d.powerInfo()
+		for i, kv := range info {
+			var msg []byte
+			msg = appendBytesField(msg, 1, []byte(kv[0]))
+			msg = appendBytesField(msg, 2, []byte(kv[1]))
+			s.respond(req.commandID, statusOK, i < len(info)-1, fieldSystemPowerInfoResponse, msg)
+		}
+
+	case fieldGuiStartScreenStreamRequest:
+		s.mu.Lock()
+		s.streaming = true
+		s.mu.Unlock()
+		s.respond(req.commandID, statusOK, false, fieldEmpty, nil)
+
+	case fieldGuiStopScreenStreamRequest:
+		s.mu.Lock()
+		s.streaming = false
+		s.mu.Unlock()
+		s.respond(req.commandID, statusOK, false, fieldEmpty, nil)
+
+	case fieldGuiSendInputEventRequest:
+		s.d.input(flipper.InputKey(getVarint(req.fields, 1)), flipper.InputType(getVarint(req.fields, 2)))
+		s.respond(req.commandID, statusOK, false, fieldEmpty, nil)
+
+	case fieldAppStartRequest:
+		status := s.d.startApp(string(getBytes(req.fields, 1)), string(getBytes(req.fields, 2)))
+		s.respond(req.commandID, status, false, fieldEmpty, nil)
+
+	case fieldAppLockStatusRequest:
+		var locked uint64
+		if s.d.appLocked() {
+			locked = 1
+		}
+		s.respond(req.commandID, statusOK, false, fieldAppLockStatusResponse, appendVarintField(nil, 1, locked))
+
+	case fieldStorageListRequest:
+		s.handleList(req)
+
+	case fieldStorageReadRequest:
+		s.handleRead(req)
+
+	case fieldStorageStatRequest:
+		s.handleStat(req)
+
+	case fieldStorageWriteRequest:
+		s.handleWrite(req)
+
+	case fieldStorageDeleteRequest:
+		status := s.d.storage.remove(string(getBytes(req.fields, 1)), getVarint(req.fields, 2) != 0)
+		s.respond(req.commandID, status, false, fieldEmpty, nil)
+
+	case fieldStorageMkdirRequest:
+		status := s.d.storage.mkdir(string(getBytes(req.fields, 1)))
+		s.respond(req.commandID, status, false, fieldEmpty, nil)
+
+	case fieldStorageRenameRequest:
+		status := s.d.storage.rename(string(getBytes(req.fields, 1)), string(getBytes(req.fields, 2)))
+		s.respond(req.commandID, status, false, fieldEmpty, nil)
+
+	case fieldStorageMd5SumRequest:
+		s.handleMd5Sum(req)
+
+	case fieldStorageInfoRequest:
+		var msg []byte
+		msg = appendVarintField(msg, 1, storageSize)
+		msg = appendVarintField(msg, 2, storageSize-s.d.storage.used())
+		s.respond(req.commandID, statusOK, false, fieldStorageInfoResponse, msg)
+
+	default:
+		s.respond(req.commandID, statusErrorNotImplemented, false, fieldEmpty, nil)
+	}
+}
+
+// handleList lists a directory. The files are split into multiple responses.
+func (s *session) handleList(req *request) {
+	entries, ok := s.d.storage.list(string(getBytes(req.fields, 1)))
+	if !ok {
+		s.respond(req.commandID, statusErrorStorageNotExist, false, fieldEmpty, nil)
+		return
+	}
+	if len(entries) == 0 {
+		s.respond(req.commandID, statusOK, false, fieldStorageListResponse, nil)
+		return
+	}
+	for i := 0; i < len(entries); i += maxListEntries {
+		end := min(i+maxListEntries, len(entries))
+		var msg []byte
+		for _, e := range entries[i:end] {
+			msg = appendMessageField(msg, 1, encodeEntry(e))
+		}
+		s.respond(req.commandID, statusOK, end < len(entries), fieldStorageListResponse, msg)
+	}
+}
+
+// handleRead reads a file. The data is split into multiple responses.
+func (s *session) handleRead(req *request) {
+	n, ok := s.d.storage.get(string(getBytes(req.fields, 1)))
+	if !ok || n.dir {
+		s.respond(req.commandID, statusErrorStorageNotExist, false, fieldEmpty, nil)
+		return
+	}
+	data := n.data
+	if len(data) == 0 {
+		s.respond(req.commandID, statusOK, false, fieldStorageReadResponse, appendMessageField(nil, 1, nil))
+		return
+	}
+	for i := 0; i < len(data); i += maxPayloadLength {
+		end := min(i+maxPayloadLength, len(data))
+		msg := appendMessageField(nil, 1, encodeFile(0, "", 0, data[i:end]))
+		s.respond(req.commandID, statusOK, end < len(data), fieldStorageReadResponse, msg)
+	}
+}
+
+// handleStat returns information about a single file or directory.
+func (s *session) handleStat(req *request) {
+	p := string(getBytes(req.fields, 1))
+	n, ok := s.d.storage.get(p)
+	if !ok {
+		s.respond(req.commandID, statusErrorStorageNotExist, false, fieldEmpty, nil)
+		return
+	}
+	msg := appendMessageField(nil, 1, encodeEntry(entry{name: path.Base(p), node: n}))
+	s.respond(req.commandID, statusOK, false, fieldStorageStatResponse, msg)
+}
+
+// handleWrite writes a file. The data may be split into multiple requests,
+// the response is sent once the last part was received.
+func (s *session) handleWrite(req *request) {
+	w := s.write
+	if w == nil || w.commandID != req.commandID {
+		p := string(getBytes(req.fields, 1))
+		w = &pendingWrite{commandID: req.commandID, path: p, status: s.d.storage.writable(p)}
+		s.write = w
+	}
+	file, err := decodeFields(getBytes(req.fields, 2))
+	if err != nil {
+		w.status = statusErrorDecode
+	}
+	w.data = append(w.data, getBytes(file, 4)...)
+	if req.hasNext {
+		return
+	}
+	s.write = nil
+	if w.status == statusOK {
+		s.d.storage.writeFile(w.path, w.data)
+	}
+	s.respond(req.commandID, w.status, false, fieldEmpty, nil)
+}
+
+// handleMd5Sum returns the hex encoded md5 sum of a file.
+func (s *session) handleMd5Sum(req *request) {
+	n, ok := s.d.storage.get(string(getBytes(req.fields, 1)))
+	if !ok || n.dir {
+		s.respond(req.commandID, statusErrorStorageNotExist, false, fieldEmpty, nil)
+		return
+	}
+	sum := md5.Sum(n.data)
+	s.respond(req.commandID, statusOK, false, fieldStorageMd5SumResponse, appendBytesField(nil, 1, []byte(hex.EncodeToString(sum[:]))))
+}
+
+// encodeEntry encodes a directory entry as PB_Storage.File.
+func encodeEntry(e entry) []byte {
+	if e.dir {
+		return encodeFile(uint64(flipper.FileTypeDir), e.name, 0, nil)
+	}
+	return encodeFile(uint64(flipper.FileTypeFile), e.name, uint32(len(e.data)), nil)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/flipperui"
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+// demoDevices are the names of the simulated flippers used in demo mode.
+var demoDevices = []string{"Alpha", "Bravo", "Charlie"}
+
+// fleetTarget describes how to reach a single flipper of a fleet.
+type fleetTarget struct {
+	name string
+	opts []recfz.Opts
+}
+
+// fleetTargets returns the flippers of a fleet.
+// Arguments that look like a path or an address are used as port, everything else as device name.
+// Without arguments, all attached flippers are used.
+func fleetTargets(args []string) ([]fleetTarget, error) {
+	var targets []fleetTarget
+	if rootFlags.demo {
+		for _, name := range demoDevices {
+			dev := fakefz.NewDevice(fakefz.WithName(name))
+			targets = append(targets, fleetTarget{
+				name: name,
+				opts: []recfz.Opts{recfz.WithTransport(dev.Transport())},
+			})
+		}
+		return targets, nil
+	}
+
+	for _, arg := range args {
+		if isPort(arg) {
+			targets = append(targets, fleetTarget{name: arg, opts: []recfz.Opts{recfz.WithPort(arg)}})
+			continue
+		}
+		targets = append(targets, fleetTarget{name: arg, opts: []recfz.Opts{recfz.WithDeviceName(arg)}})
+	}
+	if len(targets) > 0 {
+		return targets, nil
+	}
+
+	devices, err := recfz.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no flipper found")
+	}
+	for _, d := range devices {
+		// the serial number survives a changed port after replugging
+		targets = append(targets, fleetTarget{name: d.Name, opts: []recfz.Opts{recfz.WithSerialNumber(d.SerialNumber)}})
+	}
+	return targets, nil
+}
+
+// isPort returns true if the argument looks like a serial port or a tcp address.
+func isPort(arg string) bool {
+	return strings.Contains(arg, "://") ||
+		strings.ContainsAny(arg, `/\`) ||
+		strings.HasPrefix(strings.ToUpper(arg), "COM")
+}
+
+// fleetFlags are the flags of the commands sending input to a fleet.
+var fleetFlags struct {
+	settle        time.Duration
+	settleTimeout time.Duration
+}
+
+// addFleetFlags adds the fleet flags to a command.
+func addFleetFlags(cmd *coral.Command) {
+	cmd.Flags().DurationVar(&fleetFlags.settle, "settle", 0, "wait until no screen changed for this duration before accepting the next input (0: don't wait)")
+	cmd.Flags().DurationVar(&fleetFlags.settleTimeout, "settle-timeout", 5*time.Second, "stop waiting for the screens to settle after this duration")
+}
+
+// fleetOpts returns the options of a fleet according to the flags.
+func fleetOpts() []recfz.FleetOpts {
+	if fleetFlags.settle <= 0 {
+		return nil
+	}
+	return []recfz.FleetOpts{recfz.WithSettle(fleetFlags.settle, fleetFlags.settleTimeout)}
+}
+
+// sendToFleet sends a key, named like in the key bindings (e.g. "up" or "shift+left"), to the whole fleet.
+func sendToFleet(ctx context.Context, fleet *recfz.Fleet, key string) ([]recfz.Result, error) {
+	event, long := flipperui.ParseKey(key)
+	if event == -1 {
+		return nil, fmt.Errorf("unknown key %q", key)
+	}
+	if long {
+		return fleet.SendLongPress(ctx, event), nil
+	}
+	return fleet.SendShortPress(ctx, event), nil
+}
+
+// formatResult describes the result of an input sent to a single flipper.
+func formatResult(r recfz.Result) string {
+	switch {
+	case r.Err != nil:
+		return "failed: " + r.Err.Error()
+	case fleetFlags.settle > 0 && !r.Settled:
+		return "ok, screen didn't settle"
+	}
+	return "ok"
+}
@@ -0,0 +1,113 @@
+package flipperui
+
+import (
+	"sync"
+
+	"github.com/flipperdevices/go-flipper"
+)
+
+// Broadcaster fans out screen updates from a single flipper to multiple subscribers.
+// Every subscriber gets its own mailbox which only ever holds the latest frame,
+// so a slow or idle subscriber never blocks the flipper or the other subscribers.
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// last is the last screen that was published. New subscribers receive it immediately.
+	last *ScreenMsg
+}
+
+// Subscription represents a single subscriber of a Broadcaster.
+type Subscription struct {
+	b      *Broadcaster
+	ch     chan ScreenMsg
+	closed bool
+}
+
+// NewBroadcaster creates a new screen broadcaster.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Callback returns a function that publishes every frame to all subscribers.
+// It is intended to be used as a callback for the flipper (see recfz.WithStreamScreenCallback).
+func (b *Broadcaster) Callback() func(frame flipper.ScreenFrame) {
+	return func(frame flipper.ScreenFrame) {
+		b.Publish(ScreenMsg{frame: frame})
+	}
+}
+
+// Publish sends a screen update to all subscribers.
+// If a subscriber didn't read the previous update yet, it will be replaced by the new one.
+func (b *Broadcaster) Publish(msg ScreenMsg) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = &msg
+	for s := range b.subs {
+		s.offer(msg)
+	}
+}
+
+// Subscribe registers a new subscriber.
+// The subscription is primed with the last known screen, if there is one.
+func (b *Broadcaster) Subscribe() *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &Subscription{
+		b:  b,
+		ch: make(chan ScreenMsg, 1),
+	}
+	if b.last != nil {
+		s.ch <- *b.last
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes a subscriber and closes its update channel.
+// It is safe to call Unsubscribe multiple times.
+func (b *Broadcaster) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(b.subs, s)
+	close(s.ch)
+}
+
+// Subscribers returns the number of active subscribers.
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Updates returns the channel on which the subscriber receives screen updates.
+// The channel is closed once the subscription is closed.
+func (s *Subscription) Updates() <-chan ScreenMsg {
+	return s.ch
+}
+
+// Close unsubscribes from the broadcaster.
+func (s *Subscription) Close() {
+	s.b.Unsubscribe(s)
+}
+
+// offer puts the msg into the mailbox, replacing an unread msg if necessary.
+// The caller must hold the broadcaster lock.
+func (s *Subscription) offer(msg ScreenMsg) {
+	select {
+	case s.ch <- msg:
+		return
+	default:
+	}
+	// drop the stale frame
+	select {
+	case <-s.ch:
+	default:
+	}
+	s.ch <- msg
+}
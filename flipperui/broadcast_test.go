@@ -0,0 +1,77 @@
+package flipperui
+
+import "testing"
+
+// frame returns a screen update which is told apart by its id.
+func frame(n int) ScreenMsg {
+	return ScreenMsg{id: n}
+}
+
+// receive returns the pending update of a subscription, or -1 if there is none.
+func receive(t *testing.T, s *Subscription) int {
+	t.Helper()
+	select {
+	case msg, ok := <-s.Updates():
+		if !ok {
+			t.Fatal("updates are closed")
+		}
+		return msg.id
+	default:
+		return -1
+	}
+}
+
+func TestBroadcasterKeepsLatestFrame(t *testing.T) {
+	b := NewBroadcaster()
+	fast, slow := b.Subscribe(), b.Subscribe()
+
+	for i := 1; i <= 3; i++ {
+		b.Publish(frame(i))
+		if got := receive(t, fast); got != i {
+			t.Fatalf("expected frame %d, got %d", i, got)
+		}
+	}
+	// the slow subscriber only gets the latest frame and never blocks the others
+	if got := receive(t, slow); got != 3 {
+		t.Fatalf("expected frame 3, got %d", got)
+	}
+	if got := receive(t, slow); got != -1 {
+		t.Fatalf("expected no frame, got %d", got)
+	}
+}
+
+func TestBroadcasterPrimesNewSubscribers(t *testing.T) {
+	b := NewBroadcaster()
+	if got := receive(t, b.Subscribe()); got != -1 {
+		t.Fatalf("expected no frame before the first one was published, got %d", got)
+	}
+	b.Publish(frame(1))
+	b.Publish(frame(2))
+	if got := receive(t, b.Subscribe()); got != 2 {
+		t.Fatalf("expected frame 2, got %d", got)
+	}
+}
+
+func TestBroadcasterUnsubscribe(t *testing.T) {
+	b := NewBroadcaster()
+	s, other := b.Subscribe(), b.Subscribe()
+	if n := b.Subscribers(); n != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", n)
+	}
+
+	s.Close()
+	// closing twice is fine
+	b.Unsubscribe(s)
+	if n := b.Subscribers(); n != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", n)
+	}
+	if _, ok := <-s.Updates(); ok {
+		t.Fatal("expected the updates to be closed")
+	}
+
+	// publishing doesn't reach the closed subscription
+	b.Publish(frame(1))
+	if got := receive(t, other); got != 1 {
+		t.Fatalf("expected frame 1, got %d", got)
+	}
+}
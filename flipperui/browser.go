@@ -0,0 +1,594 @@
+package flipperui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const (
+	// maxPreviewSize is the largest file that is previewed.
+	maxPreviewSize = 64 << 10
+	// minTreeWidth is the minimum width of the tree, the preview gets the rest.
+	minTreeWidth = 28
+
+	// browserTimeout is the maximum time listing a directory or loading a preview may take.
+	browserTimeout = 10 * time.Second
+	// browserOpTimeout is the maximum time an action may take, e.g. an upload over a slow connection.
+	browserOpTimeout = 5 * time.Minute
+)
+
+// previewExtensions are the text formats that are previewed.
+var previewExtensions = map[string]bool{
+	".sub":  true,
+	".ir":   true,
+	".nfc":  true,
+	".txt":  true,
+	".rfid": true,
+	".ibtn": true,
+	".fmf":  true,
+	".js":   true,
+}
+
+// storageRoots are the top level directories of the flipper storage.
+var storageRoots = []string{"/ext", "/int"}
+
+// browserMode decides what the keys do.
+type browserMode int
+
+const (
+	browseMode browserMode = iota
+	renameMode
+	mkdirMode
+	uploadMode
+	downloadMode
+	deleteMode
+)
+
+type (
+	// browserListMsg is sent when a directory was listed.
+	browserListMsg struct {
+		id    int
+		dir   string
+		files []fs.FileInfo
+		err   error
+	}
+
+	// browserPreviewMsg is sent when a file was read for the preview.
+	browserPreviewMsg struct {
+		id      int
+		path    string
+		content string
+		err     error
+	}
+
+	// browserOpMsg is sent when an action finished. The directories in reload changed.
+	browserOpMsg struct {
+		id     int
+		status string
+		err    error
+		reload []string
+	}
+)
+
+// browserRow is a single visible row of the tree.
+type browserRow struct {
+	path  string
+	dir   bool
+	size  int64
+	depth int
+}
+
+// Browser is a file browser for the storage of the flipper.
+// It also implements the bubbletea.Model interface.
+type Browser struct {
+	fz *recfz.FlipperZero
+	// id identifies the browser, so that multiple browsers can be used in the same program
+	id int
+	// children holds the content of the listed directories
+	children map[string][]fs.FileInfo
+	// expanded holds the directories whose content is shown
+	expanded map[string]bool
+	// loading holds the directories that are being listed
+	loading map[string]bool
+	rows    []browserRow
+	cursor  int
+	offset  int
+	width   int
+	height  int
+	// preview shows the content of the selected file
+	preview     viewport.Model
+	previewPath string
+	mode        browserMode
+	input       textinput.Model
+	status      string
+	err         error
+	// localDir is the default directory for downloads
+	localDir string
+	styles   styles
+}
+
+var _ tea.Model = (*Browser)(nil)
+
+// NewBrowser constructs a new file browser.
+func NewBrowser(fz *recfz.FlipperZero) tea.Model {
+	b := Browser{
+		fz:       fz,
+		id:       nextID(),
+		children: make(map[string][]fs.FileInfo),
+		expanded: map[string]bool{"/ext": true},
+		loading:  map[string]bool{"/ext": true},
+		preview:  viewport.New(0, 0),
+		input:    textinput.New(),
+		localDir: ".",
+		width:    80,
+		height:   flipperScreenHeight,
+		styles:   newStyles(DefaultTheme),
+	}
+	b.preview.MouseWheelEnabled = false
+	b.rows = b.buildRows()
+	return &b
+}
+
+// Init is the bubbletea init function. It lists the sd card.
+func (b Browser) Init() tea.Cmd {
+	return b.list("/ext")
+}
+
+// Update is the bubbletea update function and handles all tea.Msgs.
+func (b Browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width = msg.Width
+		b.height = msg.Height
+		b.preview.Width, b.preview.Height = b.previewSize()
+		b.scroll()
+
+	case ThemeMsg:
+		b.styles = newStyles(msg.Theme)
+
+	case tea.KeyMsg:
+		if b.mode == browseMode {
+			return b.browseKey(msg)
+		}
+		return b.promptKey(msg)
+
+	case browserListMsg:
+		if msg.id != b.id {
+			return b, nil
+		}
+		delete(b.loading, msg.dir)
+		if msg.err != nil {
+			b.setStatus("", msg.err)
+			delete(b.expanded, msg.dir)
+		} else {
+			b.children[msg.dir] = msg.files
+		}
+		b.rows = b.buildRows()
+		b.scroll()
+
+	case browserPreviewMsg:
+		if msg.id != b.id || msg.path != b.previewPath {
+			return b, nil
+		}
+		content := msg.content
+		if msg.err != nil {
+			content = b.styles.err.Render(msg.err.Error())
+		}
+		b.preview.SetContent(content)
+		b.preview.GotoTop()
+
+	case browserOpMsg:
+		if msg.id != b.id {
+			return b, nil
+		}
+		b.setStatus(msg.status, msg.err)
+		var cmds []tea.Cmd
+		for _, dir := range msg.reload {
+			if b.expanded[dir] {
+				cmds = append(cmds, b.list(dir))
+			}
+		}
+		return b, tea.Batch(cmds...)
+	}
+	return b, nil
+}
+
+// browseKey handles the keys while navigating the tree.
+func (b Browser) browseKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	row, ok := b.selected()
+	switch msg.String() {
+	case "up", "k":
+		return b.move(-1)
+	case "down", "j":
+		return b.move(1)
+	case "pgup":
+		b.preview.HalfPageUp()
+	case "pgdown":
+		b.preview.HalfPageDown()
+	case "right", "l", "enter":
+		if !ok {
+			return b, nil
+		}
+		if !row.dir {
+			return b, b.loadPreview(row)
+		}
+		if !b.expanded[row.path] {
+			b.expanded[row.path] = true
+			b.rows = b.buildRows()
+			if _, listed := b.children[row.path]; !listed {
+				return b, b.list(row.path)
+			}
+		}
+	case "left", "h":
+		if !ok {
+			return b, nil
+		}
+		if row.dir && b.expanded[row.path] {
+			delete(b.expanded, row.path)
+			b.rows = b.buildRows()
+			b.scroll()
+			return b, nil
+		}
+		for i := b.cursor - 1; i >= 0; i-- {
+			if b.rows[i].depth < row.depth {
+				return b.move(i - b.cursor)
+			}
+		}
+	case "ctrl+r":
+		b.children = make(map[string][]fs.FileInfo)
+		var cmds []tea.Cmd
+		for dir := range b.expanded {
+			cmds = append(cmds, b.list(dir))
+		}
+		b.rows = b.buildRows()
+		b.scroll()
+		return b, tea.Batch(cmds...)
+	case "d":
+		if ok && !row.dir {
+			return b.prompt(downloadMode, "download to: ", filepath.Join(b.localDir, path.Base(row.path)))
+		}
+		b.setStatus("", errors.New("only files can be downloaded"))
+	case "u":
+		if ok {
+			return b.prompt(uploadMode, "upload file: ", "")
+		}
+	case "r":
+		if ok && !isStorageRoot(row.path) {
+			return b.prompt(renameMode, "rename to: ", path.Base(row.path))
+		}
+	case "n":
+		if ok {
+			return b.prompt(mkdirMode, "new folder: ", "")
+		}
+	case "x", "delete":
+		if ok && !isStorageRoot(row.path) {
+			b.mode = deleteMode
+			b.setStatus(fmt.Sprintf("delete %s? (y/n)", row.path), nil)
+		}
+	}
+	return b, nil
+}
+
+// promptKey handles the keys while a prompt or confirmation is shown.
+func (b Browser) promptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	row, _ := b.selected()
+	if b.mode == deleteMode {
+		b.mode = browseMode
+		if msg.String() != "y" {
+			b.setStatus("", nil)
+			return b, nil
+		}
+		b.setStatus("deleting "+row.path+"…", nil)
+		return b, b.run(func(ctx context.Context) (string, error) {
+			return "deleted " + row.path, b.fz.Remove(ctx, row.path, true)
+		}, path.Dir(row.path))
+	}
+
+	switch msg.String() {
+	case "esc":
+		b.mode = browseMode
+		b.input.Blur()
+		b.setStatus("", nil)
+		return b, nil
+	case "enter":
+	default:
+		var cmd tea.Cmd
+		b.input, cmd = b.input.Update(msg)
+		return b, cmd
+	}
+
+	mode, value := b.mode, strings.TrimSpace(b.input.Value())
+	b.mode = browseMode
+	b.input.Blur()
+	if value == "" {
+		b.setStatus("", nil)
+		return b, nil
+	}
+	dir := row.path
+	if !row.dir {
+		dir = path.Dir(row.path)
+	}
+
+	switch mode {
+	case renameMode:
+		target := path.Join(path.Dir(row.path), value)
+		b.setStatus("renaming "+row.path+"…", nil)
+		return b, b.run(func(ctx context.Context) (string, error) {
+			return "renamed " + row.path + " to " + target, b.fz.Rename(ctx, row.path, target)
+		}, path.Dir(row.path))
+
+	case mkdirMode:
+		target := path.Join(dir, value)
+		b.expanded[dir] = true
+		return b, b.run(func(ctx context.Context) (string, error) {
+			return "created " + target, b.fz.Mkdir(ctx, target)
+		}, dir)
+
+	case uploadMode:
+		target := path.Join(dir, filepath.Base(value))
+		b.expanded[dir] = true
+		b.setStatus("uploading "+value+"…", nil)
+		return b, b.run(func(ctx context.Context) (string, error) {
+			f, err := os.Open(value)
+			if err != nil {
+				return "", err
+			}
+			defer f.Close()
+			n, err := b.fz.WriteFile(ctx, target, f, nil)
+			return fmt.Sprintf("uploaded %s (%s)", target, recfz.FormatSize(n)), err
+		}, dir)
+
+	case downloadMode:
+		b.localDir = filepath.Dir(value)
+		b.setStatus("downloading "+row.path+"…", nil)
+		return b, b.run(func(ctx context.Context) (string, error) {
+			var buf bytes.Buffer
+			if _, err := b.fz.ReadFile(ctx, row.path, &buf, nil); err != nil {
+				return "", err
+			}
+			if err := os.WriteFile(value, buf.Bytes(), 0o644); err != nil { //nolint:gosec
+				return "", err
+			}
+			return fmt.Sprintf("downloaded %s (%s)", value, recfz.FormatSize(int64(buf.Len()))), nil
+		})
+	}
+	return b, nil
+}
+
+// prompt asks for a value, e.g. the new name of a file.
+func (b Browser) prompt(mode browserMode, prompt, value string) (tea.Model, tea.Cmd) {
+	b.mode = mode
+	b.err = nil
+	b.input.Prompt = prompt
+	b.input.SetValue(value)
+	b.input.CursorEnd()
+	return b, b.input.Focus()
+}
+
+// move moves the cursor and previews the selected file.
+func (b Browser) move(delta int) (tea.Model, tea.Cmd) {
+	b.cursor = max(0, min(b.cursor+delta, len(b.rows)-1))
+	b.scroll()
+	if row, ok := b.selected(); ok && !row.dir {
+		return b, b.loadPreview(row)
+	}
+	return b, nil
+}
+
+// selected returns the row under the cursor.
+func (b Browser) selected() (browserRow, bool) {
+	if b.cursor < 0 || b.cursor >= len(b.rows) {
+		return browserRow{}, false
+	}
+	return b.rows[b.cursor], true
+}
+
+// setStatus sets the message shown below the tree.
+func (b *Browser) setStatus(status string, err error) {
+	b.status = status
+	b.err = err
+}
+
+// scroll keeps the cursor inside the tree and visible.
+func (b *Browser) scroll() {
+	b.cursor = max(0, min(b.cursor, len(b.rows)-1))
+	h := b.treeHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+h {
+		b.offset = b.cursor - h + 1
+	}
+}
+
+// list lists a directory in the background.
+func (b *Browser) list(dir string) tea.Cmd {
+	b.loading[dir] = true
+	id, fz := b.id, b.fz
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), browserTimeout)
+		defer cancel()
+		files, err := fz.List(ctx, dir)
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].IsDir() != files[j].IsDir() {
+				return files[i].IsDir()
+			}
+			return files[i].Name() < files[j].Name()
+		})
+		return browserListMsg{id: id, dir: dir, files: files, err: err}
+	}
+}
+
+// loadPreview reads a text file in the background.
+func (b *Browser) loadPreview(row browserRow) tea.Cmd {
+	if row.path == b.previewPath {
+		return nil
+	}
+	b.previewPath = row.path
+	switch {
+	case !previewExtensions[strings.ToLower(path.Ext(row.path))]:
+		b.preview.SetContent(b.styles.dim.Render("no preview"))
+		return nil
+	case row.size > maxPreviewSize:
+		b.preview.SetContent(b.styles.dim.Render("too large for a preview"))
+		return nil
+	}
+	b.preview.SetContent(b.styles.dim.Render("loading…"))
+	id, fz := b.id, b.fz
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), browserTimeout)
+		defer cancel()
+		var buf bytes.Buffer
+		_, err := fz.ReadFile(ctx, row.path, &buf, nil)
+		content := strings.ReplaceAll(buf.String(), "\t", "    ")
+		if err == nil && !utf8.ValidString(content) {
+			content = b.styles.dim.Render("binary file")
+		}
+		return browserPreviewMsg{id: id, path: row.path, content: content, err: err}
+	}
+}
+
+// run runs an action in the background and reloads the given directories afterwards.
+func (b Browser) run(fn func(ctx context.Context) (string, error), reload ...string) tea.Cmd {
+	id := b.id
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), browserOpTimeout)
+		defer cancel()
+		status, err := fn(ctx)
+		return browserOpMsg{id: id, status: status, err: err, reload: reload}
+	}
+}
+
+// buildRows flattens the expanded directories into the visible rows.
+func (b Browser) buildRows() []browserRow {
+	var rows []browserRow
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		if !b.expanded[dir] {
+			return
+		}
+		for _, f := range b.children[dir] {
+			p := path.Join(dir, f.Name())
+			rows = append(rows, browserRow{path: p, dir: f.IsDir(), size: f.Size(), depth: depth})
+			walk(p, depth+1)
+		}
+	}
+	for _, root := range storageRoots {
+		rows = append(rows, browserRow{path: root, dir: true})
+		walk(root, 1)
+	}
+	return rows
+}
+
+// treeHeight is the number of rows of the tree that fit on the screen.
+func (b Browser) treeHeight() int {
+	// title and status line
+	return max(1, b.height-2)
+}
+
+// treeWidth is the width of the tree, the preview gets the rest.
+func (b Browser) treeWidth() int {
+	return max(minTreeWidth, b.width*2/5)
+}
+
+// previewSize returns the size of the preview next to the tree.
+func (b Browser) previewSize() (int, int) {
+	// the border and the padding take two columns
+	return max(0, b.width-b.treeWidth()-2), b.treeHeight()
+}
+
+// View renders the tree, the preview and the status line.
+func (b Browser) View() string {
+	title := "storage"
+	if row, ok := b.selected(); ok {
+		title = row.path
+	}
+	title = b.styles.title.Width(b.width).MaxWidth(b.width).Render("📂 " + title)
+
+	treeWidth := b.treeWidth()
+	var lines []string
+	for i := b.offset; i < len(b.rows) && i < b.offset+b.treeHeight(); i++ {
+		lines = append(lines, b.rowView(b.rows[i], i == b.cursor, treeWidth))
+	}
+	tree := lipgloss.NewStyle().Width(treeWidth).Height(b.treeHeight()).MaxWidth(treeWidth).Render(strings.Join(lines, "\n"))
+
+	body := tree
+	if w, h := b.previewSize(); w > 0 {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, tree,
+			b.styles.previewBorder.Height(h).MaxHeight(h).Render(lipgloss.NewStyle().MaxWidth(w).Render(b.preview.View())),
+		)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, body, b.statusView())
+}
+
+// rowView renders a single row of the tree.
+func (b Browser) rowView(row browserRow, selected bool, width int) string {
+	name := path.Base(row.path)
+	if row.depth == 0 {
+		name = row.path
+	}
+	indent := strings.Repeat("  ", row.depth)
+	var icon, size string
+	switch {
+	case !row.dir:
+		icon, size = "  ", recfz.FormatSize(row.size)
+	case b.loading[row.path]:
+		icon = "… "
+	case b.expanded[row.path]:
+		icon = "▾ "
+	default:
+		icon = "▸ "
+	}
+	label := indent + icon + name
+	if row.dir {
+		label = indent + icon + b.styles.dir.Render(name+"/")
+	}
+	cursor := " "
+	if selected {
+		cursor = b.styles.cursor.Render("›")
+	}
+	pad := max(1, width-2-lipgloss.Width(label)-len(size))
+	return cursor + label + strings.Repeat(" ", pad) + b.styles.dim.Render(size)
+}
+
+// statusView renders the prompt, the last result or the key help.
+func (b Browser) statusView() string {
+	var s string
+	switch {
+	case b.mode != browseMode && b.mode != deleteMode:
+		s = b.input.View()
+	case b.err != nil:
+		s = b.styles.err.Render(b.err.Error())
+	case b.status != "":
+		s = b.status
+	default:
+		s = b.styles.dim.Render("enter open • d download • u upload • r rename • x delete • n new folder • ctrl+r reload")
+	}
+	return lipgloss.NewStyle().MaxWidth(b.width).Render(s)
+}
+
+// isStorageRoot returns true for /ext and /int, they can't be renamed or deleted.
+func isStorageRoot(p string) bool {
+	for _, root := range storageRoots {
+		if p == root {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,12 @@
 package flipperui
 
 import (
+	"errors"
 	"fmt"
-	"image"
 	"image/png"
 	"os"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -30,24 +30,40 @@ const (
 	// fzEventCoolDown is the time that must pass between two events that are sent to the flipper.
 	// That poor serial connection can handle only so much :(
 	fzEventCoolDown = time.Millisecond * 10
+
+	// maxScale is the largest scale the screen is fit into the terminal with.
+	maxScale = 8
 )
 
-var (
-	// colors of the flipper screen
-	colorBg = lipgloss.Color("#FF8C00")
-	colorFg = lipgloss.Color("#000000")
+const (
+	// default colors of the flipper screen
+	defaultBgColor = "#FF8C00"
+	defaultFgColor = "#000000"
 )
 
+// lastID is the last id that was assigned to a flipper model.
+var lastID int64
+
+// nextID returns a unique id for a flipper model.
+func nextID() int {
+	return int(atomic.AddInt64(&lastID, 1))
+}
+
 type (
 	// ScreenMsg is a message that is sent when the flipper sends a screen update.
 	ScreenMsg struct {
-		screen string
-		image  image.Image
+		// id is the id of the model that receives the message.
+		id    int
+		frame flipper.ScreenFrame
 	}
-)
 
-// ErrStyle is the style of the error message
-var ErrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+	// StateMsg is a message that is sent when the connection state of the flipper changes.
+	StateMsg struct {
+		// id is the id of the model that receives the message.
+		id int
+		recfz.Event
+	}
+)
 
 // Model represents the flipper model.
 // It also implements the bubbletea.Model interface.
@@ -60,14 +76,16 @@ type Model struct {
 	err error
 	// errTime is the time when the last error occurred
 	errTime time.Time
-	// content is the current screen of the flipper as a string
+	// content is the current screen of the flipper rendered by the renderer
 	content string
 	// lastFZEvent is the time of the last event that was sent to the flipper.
 	lastFZEvent time.Time
 	// screenUpdate is a channel that receives screen updates from the flipper
 	screenUpdate <-chan ScreenMsg
-	// currentScreen is the last screen that was received from the flipper
-	currentScreen image.Image
+	// currentFrame is the last screen that was received from the flipper
+	currentFrame *flipper.ScreenFrame
+	// id identifies the model, so that multiple models can be used in the same program
+	id int
 	// mutex to ensure that only one goroutine can send events to the flipper at a time
 	mu *sync.Mutex
 	// resolution of the screenshots
@@ -75,12 +93,36 @@ type Model struct {
 		width  int
 		height int
 	}
-	// Style is the style of the flipper screen
-	Style lipgloss.Style
-	// bgColor is the background color of the flipper screen
-	bgColor string
-	// fgColor is the foreground color of the flipper screen
-	fgColor string
+	// theme is the color scheme of the screen and the status bar
+	theme Theme
+	// themeSet is true if the theme was set by WithTheme, it's sent to the other models when the model starts
+	themeSet bool
+	// themes are the themes cycled through with ctrl+o
+	themes []Theme
+	// styles are derived from the theme
+	styles styles
+	// inputAllowed decides if input events are sent to the flipper. If nil, all events are sent.
+	inputAllowed func() bool
+	// stateUpdate is a channel that receives connection state changes from the flipper
+	stateUpdate <-chan recfz.Event
+	// state is the last known connection state of the flipper
+	state recfz.Event
+	// statusBarEnabled decides if the status bar is shown below the screen
+	statusBarEnabled bool
+	// status holds the values shown in the status bar
+	status statusBar
+	// height is the height of the terminal, the status bar is hidden if it doesn't fit
+	height int
+	// renderers are the renderers to pick from, the largest first
+	renderers []Renderer
+	// renderer draws the screen in the terminal
+	renderer Renderer
+	// scale is the scale the renderer draws the screen at
+	scale int
+	// fit decides if the renderer and the scale are picked on every resize to fit the screen into the terminal
+	fit bool
+	// skin draws the body of the flipper with clickable buttons around the screen
+	skin skin
 }
 
 var _ tea.Model = (*Model)(nil)
@@ -100,8 +142,11 @@ func New(fz *recfz.FlipperZero, screenUpdate <-chan ScreenMsg, opts ...FlipperOp
 			width:  1024,
 			height: 512,
 		},
-		bgColor: "#FF8C00",
-		fgColor: "#000000",
+		theme:     DefaultTheme,
+		themes:    Themes,
+		id:        nextID(),
+		renderers: []Renderer{halfBlockRenderer},
+		skin:      skin{lit: -1},
 	}
 	m.viewport.MouseWheelEnabled = false
 
@@ -109,24 +154,64 @@ func New(fz *recfz.FlipperZero, screenUpdate <-chan ScreenMsg, opts ...FlipperOp
 		opt(&m)
 	}
 
-	colorBg = lipgloss.Color(m.bgColor)
-	colorFg = lipgloss.Color(m.fgColor)
-
-	m.Style = lipgloss.NewStyle().Background(colorBg).Foreground(colorFg)
+	m.styles = newStyles(m.theme)
+	if len(m.renderers) == 0 {
+		m.renderers = []Renderer{halfBlockRenderer}
+	}
+	m.renderer = m.renderers[0]
+	if m.scale <= 0 {
+		m.fit = true
+		m.scale = m.renderer.DefaultScale()
+	}
+	m.viewport.Width, m.viewport.Height = m.renderer.Size(m.scale)
 
 	return &m
 }
 
 // Init is the bubbletea init function.
-// the initial listenScreenUpdate command is started here.
+// the initial listenScreenUpdate command is started here and a theme set by WithTheme is sent to the other models.
 func (m Model) Init() tea.Cmd {
-	return listenScreenUpdate(m.screenUpdate)
+	cmds := []tea.Cmd{listenScreenUpdate(m.id, m.screenUpdate)}
+	if m.themeSet {
+		id, theme := m.id, m.theme
+		cmds = append(cmds, func() tea.Msg { return ThemeMsg{id: id, Theme: theme} })
+	}
+	if m.stateUpdate != nil {
+		cmds = append(cmds, listenStateUpdate(m.id, m.stateUpdate))
+	}
+	if m.statusBarEnabled {
+		cmds = append(cmds, queryName(m.id, m.fz), queryPower(m.id, m.fz), statusTick(m.id))
+	}
+	return tea.Batch(cmds...)
+}
+
+// ID returns the unique id of the model.
+func (m Model) ID() int {
+	return m.id
 }
 
 // listenScreenUpdate listens for screen updates from the flipper and returns them as tea.Cmds.
-func listenScreenUpdate(u <-chan ScreenMsg) tea.Cmd {
+// If the update channel is closed, it stops listening.
+func listenScreenUpdate(id int, u <-chan ScreenMsg) tea.Cmd {
 	return func() tea.Msg {
-		return <-u
+		msg, ok := <-u
+		if !ok {
+			return nil
+		}
+		msg.id = id
+		return msg
+	}
+}
+
+// listenStateUpdate listens for connection state changes of the flipper and returns them as tea.Cmds.
+// If the state channel is closed, it stops listening.
+func listenStateUpdate(id int, u <-chan recfz.Event) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-u
+		if !ok {
+			return nil
+		}
+		return StateMsg{id: id, Event: e}
 	}
 }
 
@@ -142,29 +227,100 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlS:
 			m.saveImage()
 			return m, nil
+		case tea.KeyCtrlO:
+			return m, m.cycleTheme()
 		default:
 			key, getlong := mapKey(msg)
 			if key != -1 {
 				m.sendFlipperEvent(key, getlong)
+				if m.skin.enabled {
+					cmds = append(cmds, m.light(key))
+				}
 			}
 		}
 
 	case tea.MouseMsg:
+		if m.skin.enabled {
+			cmds = append(cmds, m.click(msg))
+		}
 		event := mapMouse(msg)
 		if event != -1 {
 			m.sendFlipperEvent(event, false)
 		}
 
+	case ThemeMsg:
+		if msg.id == m.id {
+			m.setTheme(msg.Theme)
+		}
+
+	case skinReleaseMsg:
+		if msg.id == m.id && msg.seq == m.skin.seq && !m.skin.clicked {
+			m.skin.lit = -1
+		}
+
+	case skinHoldMsg:
+		if msg.id == m.id {
+			m.hold(msg)
+		}
+
 	case tea.WindowSizeMsg:
-		m.viewport.Width = min(msg.Width, flipperScreenWidth)
-		m.viewport.Height = min(msg.Height, flipperScreenHeight)
-		m.viewport.SetContent(m.Style.Render(m.content))
+		m.height = msg.Height
+		// the space left for the screen
+		width, height := msg.Width, msg.Height
+		if m.skin.enabled {
+			width, height = max(0, width-skinWidth), max(0, height-skinHeight)
+		}
+		if m.fit {
+			h := height
+			if m.statusBarEnabled {
+				h -= statusBarHeight
+			}
+			m.fitScreen(width, h)
+		}
+		screenWidth, screenHeight := m.renderer.Size(m.scale)
+		m.viewport.Width = min(width, screenWidth)
+		m.viewport.Height = min(height, screenHeight)
+		m.render()
 
 	case ScreenMsg:
-		m.content = msg.screen
-		m.currentScreen = msg.image
-		m.viewport.SetContent(m.Style.Render(m.content))
-		cmds = append(cmds, listenScreenUpdate(m.screenUpdate))
+		if msg.id != m.id {
+			return m, nil
+		}
+		m.currentFrame = &msg.frame
+		m.status.frame(time.Now())
+		m.render()
+		cmds = append(cmds, listenScreenUpdate(m.id, m.screenUpdate))
+
+	case StateMsg:
+		if msg.id != m.id {
+			return m, nil
+		}
+		if m.statusBarEnabled && msg.State == recfz.StateConnected && m.state.State != recfz.StateConnected {
+			cmds = append(cmds, queryName(m.id, m.fz))
+		}
+		m.state = msg.Event
+		cmds = append(cmds, listenStateUpdate(m.id, m.stateUpdate))
+
+	case nameMsg:
+		if msg.id == m.id {
+			m.status.name = msg.name
+		}
+
+	case statusTickMsg:
+		if msg.id == m.id {
+			cmds = append(cmds, statusTick(m.id))
+		}
+
+	case powerMsg:
+		if msg.id == m.id {
+			m.status.power = msg.power
+			cmds = append(cmds, powerTick(m.id))
+		}
+
+	case powerTickMsg:
+		if msg.id == m.id {
+			cmds = append(cmds, queryPower(m.id, m.fz))
+		}
 	}
 
 	return m, tea.Batch(cmds...)
@@ -179,7 +335,13 @@ func min(a, b int) int {
 
 // mapKey maps a tea.KeyMsg to a flipper.InputKey
 func mapKey(key tea.KeyMsg) (flipper.InputKey, bool) {
-	switch key.String() {
+	return ParseKey(key.String())
+}
+
+// ParseKey maps the name of a key (e.g. "up", "W" or "shift+left") to a flipper.InputKey.
+// The second return value is true if the key triggers a long press. Unknown keys return -1.
+func ParseKey(key string) (flipper.InputKey, bool) {
+	switch key {
 	case "w", "up":
 		return flipper.InputKeyUp, false
 	case "a", "left":
@@ -220,71 +382,131 @@ func mapMouse(event tea.MouseMsg) flipper.InputKey {
 }
 
 // sendFlipperEvent sends an event to the flipper. It ensures that at most one event is sent every fzEventCoolDown.
+// Events are dropped if the model is not allowed to send input.
 func (m *Model) sendFlipperEvent(event flipper.InputKey, isLong bool) {
+	if m.inputAllowed != nil && !m.inputAllowed() {
+		return
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if time.Since(m.lastFZEvent) < fzEventCoolDown {
 		return
 	}
+	start := time.Now()
+	var err error
 	if !isLong {
-		m.fz.SendShortPress(event)
+		err = m.fz.SendShortPress(event)
 	} else {
-		m.fz.SendLongPress(event)
+		err = m.fz.SendLongPress(event)
+	}
+	if err == nil {
+		m.status.latency = time.Since(start)
 	}
 	m.lastFZEvent = time.Now()
 }
 
 // View renders the flipper screen or an error message if there was an error.
+// The status bar is rendered below, if it's enabled.
 func (m Model) View() string {
-	if m.err != nil && time.Since(m.errTime) < time.Second*4 {
-		return ErrStyle.Render(fmt.Sprintf("%d %s", int((time.Second*4 - time.Since(m.errTime)).Seconds()), m.err))
+	var s string
+	switch {
+	case m.err != nil && time.Since(m.errTime) < time.Second*4:
+		s = m.styles.err.Render(fmt.Sprintf("%d %s", int((time.Second*4 - time.Since(m.errTime)).Seconds()), m.err))
+	case m.stateUpdate != nil && m.state.State != recfz.StateConnected:
+		s = m.stateView()
+	default:
+		s = m.viewport.View()
+	}
+	if m.skin.enabled {
+		s = m.skinView(s)
+	}
+	if m.showStatusBar() {
+		s = lipgloss.JoinVertical(lipgloss.Left, s, m.statusBarView())
+	}
+	return s
+}
+
+// stateView renders the connection state in place of the flipper screen.
+func (m Model) stateView() string {
+	var s string
+	switch m.state.State {
+	case recfz.StateDisconnected, recfz.StateConnecting, recfz.StateHandshaking, recfz.StateReconnecting:
+		s = "connecting…"
+		if m.state.Attempt > 0 {
+			s = fmt.Sprintf("reconnecting… (attempt %d)", m.state.Attempt)
+		}
+	case recfz.StateWaiting:
+		s = "waiting for flipper…"
+	case recfz.StateLost:
+		s = "connection lost"
+	case recfz.StateClosed:
+		s = "connection closed"
+	}
+	if m.state.Err != nil {
+		s += "\n" + m.state.Err.Error()
 	}
-	return m.viewport.View()
+	return m.styles.screen.
+		Width(m.viewport.Width).
+		Height(m.viewport.Height).
+		MaxWidth(m.viewport.Width).
+		MaxHeight(m.viewport.Height).
+		Align(lipgloss.Center, lipgloss.Center).
+		Render(s)
 }
 
-// UpdateScreen renders the terminal screen based on the flipper screen.
-// It also returns the flipper screen as an image.
+// UpdateScreen sends the frames of the flipper screen to a model, which renders them.
 // This function is intended to be used as a callback for the flipper.
+// Updates are dropped if nobody is ready to receive them, use a Broadcaster
+// if multiple receivers need to be served.
 func UpdateScreen(updates chan<- ScreenMsg) func(frame flipper.ScreenFrame) {
 	return func(frame flipper.ScreenFrame) {
-		var s strings.Builder
-		for y := 0; y < 64; y += 2 {
-			var l strings.Builder
-			for x := 0; x < 128; x++ {
-				r := fullBlock
-				if !frame.IsPixelSet(x, y) && frame.IsPixelSet(x, y+1) {
-					r = lowerHalfBlock
-				}
-				if frame.IsPixelSet(x, y) && !frame.IsPixelSet(x, y+1) {
-					r = upperHalfBlock
-				}
-				if !frame.IsPixelSet(x, y) && !frame.IsPixelSet(x, y+1) {
-					r = ' '
-				}
-				l.WriteRune(r)
-			}
-			s.WriteString(l.String())
+		// make sure we don't block
+		select {
+		case updates <- ScreenMsg{frame: frame}:
+		default:
+		}
+	}
+}
 
-			// if not last line
-			if y < 62 {
-				s.WriteRune('\n')
+// fitScreen picks the first renderer showing the whole screen in the given space at the largest scale.
+// If none fits, the last renderer is used and the screen is cropped.
+func (m *Model) fitScreen(width, height int) {
+	for _, r := range m.renderers {
+		scale := 0
+		for s := 1; s <= maxScale; s++ {
+			if w, h := r.Size(s); w > width || h > height {
+				break
 			}
+			scale = s
+		}
+		if scale > 0 {
+			m.renderer, m.scale = r, scale
+			return
 		}
-		// make sure we don't block
-		go func() {
-			updates <- ScreenMsg{
-				screen: s.String(),
-				image:  frame.ToImage(colorFg, colorBg),
-			}
-		}()
 	}
+	m.renderer, m.scale = m.renderers[len(m.renderers)-1], 1
+}
+
+// render renders the current frame with the renderer.
+func (m *Model) render() {
+	if m.currentFrame == nil {
+		return
+	}
+	m.content = m.renderer.Render(*m.currentFrame, m.scale, m.theme.Fg, m.theme.Bg)
+	m.viewport.SetContent(m.content)
 }
 
 // saveImage saves the current screen as a png image.
 func (m *Model) saveImage() {
-	resImg := imaging.Resize(m.currentScreen, m.screenshotResolution.width, m.screenshotResolution.height, imaging.Box)
+	if m.currentFrame == nil {
+		m.setError(errors.New("no screen received yet"))
+		return
+	}
+	img := m.currentFrame.ToImage(themeColor(m.theme.Fg), themeColor(m.theme.Bg))
+	resImg := imaging.Resize(img, m.screenshotResolution.width, m.screenshotResolution.height, imaging.Box)
 
-	out, err := os.Create(fmt.Sprintf("flipper_%s.png", time.Now().Format("20060102150405")))
+	name := fmt.Sprintf("flipper_%s.png", time.Now().Format("20060102150405"))
+	out, err := os.Create(name)
 	if err != nil {
 		m.setError(err)
 		return
@@ -293,11 +515,14 @@ func (m *Model) saveImage() {
 
 	if err := png.Encode(out, resImg); err != nil {
 		m.setError(err)
+		return
 	}
+	m.status.lastAction = "screenshot saved to " + name
 }
 
 // setError sets the error message and the time when it occurred.
 func (m *Model) setError(err error) {
 	m.err = err
 	m.errTime = time.Now()
+	m.status.lastAction = "error: " + err.Error()
 }
@@ -0,0 +1,60 @@
+package flipperui
+
+// brailleDots are the bits of the braille dots of a 2x4 cell, row by row.
+var brailleDots = [8]rune{0x01, 0x08, 0x02, 0x10, 0x04, 0x20, 0x40, 0x80}
+
+// brailleRenderer draws 2x4 pixels per cell with braille patterns. At scale 1, the screen takes 64x16 cells.
+var brailleRenderer = glyphRenderer{
+	name:      RendererBraille,
+	cellWidth: 2, cellHeight: 4,
+	glyph: func(bits int) rune {
+		r := rune(0x2800)
+		for i, dot := range brailleDots {
+			if bits&(1<<i) != 0 {
+				r |= dot
+			}
+		}
+		return r
+	},
+}
+
+// sextantRenderer draws 2x3 pixels per cell with the sextants added in unicode 13. At scale 1, the screen takes 64x22 cells.
+// Not every font has them yet.
+var sextantRenderer = glyphRenderer{
+	name:      RendererSextant,
+	cellWidth: 2, cellHeight: 3,
+	glyph: func(bits int) rune {
+		// the sextants start at U+1FB00 and leave out the blocks which already exist
+		switch bits {
+		case 0:
+			return ' '
+		case 0b010101:
+			return '▌'
+		case 0b101010:
+			return '▐'
+		case 0b111111:
+			return fullBlock
+		}
+		r := rune(0x1FB00 + bits - 1)
+		if bits > 0b010101 {
+			r--
+		}
+		if bits > 0b101010 {
+			r--
+		}
+		return r
+	},
+}
+
+// asciiQuadrants are the characters resembling the pixels of a 2x2 cell, row by row.
+var asciiQuadrants = []rune(" '`\",|/F.\\|7_LJ#")
+
+// asciiRenderer draws 2x2 pixels per cell with ascii characters for terminals without unicode.
+// At scale 1, the screen takes 64x32 cells.
+var asciiRenderer = glyphRenderer{
+	name:      RendererASCII,
+	cellWidth: 2, cellHeight: 2,
+	glyph: func(bits int) rune {
+		return asciiQuadrants[bits]
+	},
+}
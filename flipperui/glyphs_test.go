@@ -0,0 +1,113 @@
+package flipperui
+
+import "testing"
+
+func TestHalfBlockGlyphs(t *testing.T) {
+	for bits, want := range map[int]rune{
+		0b00: ' ',
+		0b01: '▀',
+		0b10: '▄',
+		0b11: '█',
+	} {
+		if got := halfBlockRenderer.glyph(bits); got != want {
+			t.Errorf("glyph(%02b) = %q, want %q", bits, got, want)
+		}
+	}
+}
+
+func TestBrailleGlyphs(t *testing.T) {
+	for bits, want := range map[int]rune{
+		0:          '⠀',
+		0b00000001: '⠁', // top left
+		0b00000010: '⠈', // top right
+		0b00000011: '⠉',
+		0b00000100: '⠂',
+		0b00010000: '⠄',
+		0b01000000: '⡀', // bottom left
+		0b10000000: '⢀', // bottom right
+		0b01010101: '⡇', // left column
+		0b10101010: '⢸', // right column
+		0b11111111: '⣿',
+	} {
+		if got := brailleRenderer.glyph(bits); got != want {
+			t.Errorf("glyph(%08b) = %q, want %q", bits, got, want)
+		}
+	}
+}
+
+func TestSextantGlyphs(t *testing.T) {
+	for bits, want := range map[int]rune{
+		0:        ' ',
+		0b000001: '\U0001FB00', // sextant-1
+		0b000010: '\U0001FB01', // sextant-2
+		0b010100: '\U0001FB13', // sextant-35, the last one before the left half
+		0b010101: '▌',
+		0b010110: '\U0001FB14', // sextant-235, the first one after the left half
+		0b101001: '\U0001FB27', // sextant-146, the last one before the right half
+		0b101010: '▐',
+		0b101011: '\U0001FB28', // sextant-1246, the first one after the right half
+		0b111110: '\U0001FB3B', // sextant-23456
+		0b111111: '█',
+	} {
+		if got := sextantRenderer.glyph(bits); got != want {
+			t.Errorf("glyph(%06b) = %U, want %U", bits, got, want)
+		}
+	}
+
+	// every cell has its own glyph and the sextants stay inside their block
+	seen := make(map[rune]int)
+	for bits := 0; bits < 1<<6; bits++ {
+		r := sextantRenderer.glyph(bits)
+		if prev, ok := seen[r]; ok {
+			t.Errorf("glyph(%06b) = glyph(%06b) = %U", bits, prev, r)
+		}
+		seen[r] = bits
+		if r > 0xFF && r != '▌' && r != '▐' && r != '█' && (r < 0x1FB00 || r > 0x1FB3B) {
+			t.Errorf("glyph(%06b) = %U is not a sextant", bits, r)
+		}
+	}
+}
+
+func TestASCIIGlyphs(t *testing.T) {
+	if len(asciiQuadrants) != 1<<4 {
+		t.Fatalf("expected %d glyphs, got %d", 1<<4, len(asciiQuadrants))
+	}
+	for bits, want := range map[int]rune{
+		0b0000: ' ',
+		0b0001: '\'', // top left
+		0b0010: '`',  // top right
+		0b0011: '"',
+		0b0100: ',', // bottom left
+		0b0101: '|',
+		0b1000: '.', // bottom right
+		0b1100: '_',
+		0b1111: '#',
+	} {
+		if got := asciiRenderer.glyph(bits); got != want {
+			t.Errorf("glyph(%04b) = %q, want %q", bits, got, want)
+		}
+	}
+}
+
+func TestGlyphRendererSize(t *testing.T) {
+	for _, tc := range []struct {
+		renderer      glyphRenderer
+		scale         int
+		width, height int
+	}{
+		{halfBlockRenderer, 1, 128, 32},
+		{halfBlockRenderer, 2, 256, 64},
+		{brailleRenderer, 1, 64, 16},
+		{brailleRenderer, 3, 192, 48},
+		// 64 rows don't divide by 3, the last row of cells is half empty
+		{sextantRenderer, 1, 64, 22},
+		{sextantRenderer, 2, 128, 43},
+		{sextantRenderer, 3, 192, 64},
+		{asciiRenderer, 1, 64, 32},
+		{asciiRenderer, 2, 128, 64},
+	} {
+		if w, h := tc.renderer.Size(tc.scale); w != tc.width || h != tc.height {
+			t.Errorf("%s at scale %d: got %dx%d, want %dx%d", tc.renderer.name, tc.scale, w, h, tc.width, tc.height)
+		}
+	}
+}
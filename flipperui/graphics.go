@@ -0,0 +1,205 @@
+package flipperui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/flipperdevices/go-flipper"
+)
+
+const (
+	// cellWidth and cellHeight are the size of a terminal cell in pixels assumed for kitty and iterm2.
+	// The terminals are told the size of the image in cells, so the real size doesn't matter.
+	cellWidth  = 8
+	cellHeight = 16
+	// minCellWidth and minCellHeight are the smallest cells expected for sixel.
+	// The space for a sixel image is calculated with them, so the image doesn't overflow the space.
+	minCellWidth  = 6
+	minCellHeight = 12
+
+	// kittyChunkSize is the maximum size of the base64 data in a single kitty graphics escape sequence.
+	kittyChunkSize = 4096
+
+	// escape sequences to place the image
+	saveCursor    = "\x1b7"
+	restoreCursor = "\x1b8"
+)
+
+// imageProtocol encodes the screen for a terminal graphics protocol.
+type imageProtocol interface {
+	name() string
+	// size returns the number of columns and rows an image of the given size in pixels takes.
+	size(width, height int) (int, int)
+	// encode returns the escape sequence drawing the frame at the cursor, scaled up by scale
+	// and stretched to the given number of columns and rows.
+	encode(frame flipper.ScreenFrame, scale int, fg, bg color.Color, columns, rows int) string
+}
+
+// imageRenderer draws the screen as an image with a terminal graphics protocol.
+// At scale n, every pixel of the flipper is drawn as nxn pixels.
+type imageRenderer struct {
+	protocol imageProtocol
+}
+
+func newImageRenderer(p imageProtocol) imageRenderer {
+	return imageRenderer{protocol: p}
+}
+
+func (r imageRenderer) Name() string { return r.protocol.name() }
+
+func (r imageRenderer) DefaultScale() int { return 4 }
+
+func (r imageRenderer) Size(scale int) (int, int) {
+	return r.protocol.size(flipperPixelWidth*scale, flipperPixelHeight*scale)
+}
+
+// Render draws the frame as an image. The rows of the image are filled with spaces,
+// so the image takes the same space as a text renderer in the layout.
+// The escape sequence is written at the end of the last row, otherwise drawing the rows below would erase the image.
+func (r imageRenderer) Render(frame flipper.ScreenFrame, scale int, fg, bg string) string {
+	columns, rows := r.Size(scale)
+	seq := r.protocol.encode(frame, scale, themeColor(fg), themeColor(bg), columns, rows)
+
+	blank := strings.Repeat(" ", columns)
+	lines := make([]string, rows)
+	for i := range lines {
+		lines[i] = blank
+	}
+	var up string
+	if rows > 1 {
+		up = fmt.Sprintf("\x1b[%dA", rows-1)
+	}
+	lines[rows-1] = blank + saveCursor + up + fmt.Sprintf("\x1b[%dD", columns) + seq + restoreCursor
+	return strings.Join(lines, "\n")
+}
+
+// encodePNG encodes the frame scaled up by scale as png for the kitty and iterm2 protocols.
+func encodePNG(frame flipper.ScreenFrame, scale int, fg, bg color.Color) []byte {
+	img := image.NewPaletted(image.Rect(0, 0, flipperPixelWidth*scale, flipperPixelHeight*scale), color.Palette{bg, fg})
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if pixelSet(frame, x, y, scale) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	// encoding an in-memory image to a buffer can't fail
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// sixelProtocol draws images with sixel graphics.
+type sixelProtocol struct{}
+
+func (sixelProtocol) name() string { return RendererSixel }
+
+func (sixelProtocol) size(width, height int) (int, int) {
+	return (width + minCellWidth - 1) / minCellWidth, (height + minCellHeight - 1) / minCellHeight
+}
+
+// encode encodes the frame as sixels. Every band of six rows is drawn once per color, with repeated sixels run-length encoded.
+func (sixelProtocol) encode(frame flipper.ScreenFrame, scale int, fg, bg color.Color, _, _ int) string {
+	width, height := flipperPixelWidth*scale, flipperPixelHeight*scale
+	var s strings.Builder
+	s.WriteString("\x1bP0;1;0q")
+	fmt.Fprintf(&s, "\"1;1;%d;%d", width, height)
+	// color 0 is the background, color 1 the foreground
+	for i, c := range []color.Color{bg, fg} {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&s, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+	for band := 0; band < height; band += 6 {
+		for i, set := range []bool{false, true} {
+			if _, _, _, a := bg.RGBA(); !set && a == 0 {
+				continue
+			}
+			fmt.Fprintf(&s, "#%d", i)
+			var last byte
+			run := 0
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					if pixelSet(frame, x, band+dy, scale) == set {
+						bits |= 1 << dy
+					}
+				}
+				ch := '?' + bits
+				if ch != last && run > 0 {
+					writeSixelRun(&s, last, run)
+					run = 0
+				}
+				last = ch
+				run++
+			}
+			writeSixelRun(&s, last, run)
+			s.WriteByte('$')
+		}
+		s.WriteByte('-')
+	}
+	s.WriteString("\x1b\\")
+	return s.String()
+}
+
+// writeSixelRun writes a sixel repeated n times.
+func writeSixelRun(s *strings.Builder, ch byte, n int) {
+	if n > 3 {
+		fmt.Fprintf(s, "!%d%c", n, ch)
+		return
+	}
+	for i := 0; i < n; i++ {
+		s.WriteByte(ch)
+	}
+}
+
+// kittyProtocol draws images with the kitty graphics protocol.
+// Every frame replaces the image with the same id, so the terminal doesn't pile up old frames.
+type kittyProtocol struct {
+	id int
+}
+
+func (kittyProtocol) name() string { return RendererKitty }
+
+func (kittyProtocol) size(width, height int) (int, int) {
+	return width / cellWidth, height / cellHeight
+}
+
+func (p kittyProtocol) encode(frame flipper.ScreenFrame, scale int, fg, bg color.Color, columns, rows int) string {
+	data := base64.StdEncoding.EncodeToString(encodePNG(frame, scale, fg, bg))
+	var s strings.Builder
+	for first := true; first || data != ""; first = false {
+		chunk := data[:min(kittyChunkSize, len(data))]
+		data = data[len(chunk):]
+		more := 0
+		if data != "" {
+			more = 1
+		}
+		if first {
+			// C=1 keeps the cursor in place, q=2 suppresses the responses
+			fmt.Fprintf(&s, "\x1b_Ga=T,f=100,i=%d,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", p.id, columns, rows, more, chunk)
+			continue
+		}
+		fmt.Fprintf(&s, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+	}
+	return s.String()
+}
+
+// iterm2Protocol draws images with the inline images protocol of iterm2, which is also supported by e.g. wezterm.
+type iterm2Protocol struct{}
+
+func (iterm2Protocol) name() string { return RendererITerm2 }
+
+func (iterm2Protocol) size(width, height int) (int, int) {
+	return width / cellWidth, height / cellHeight
+}
+
+func (iterm2Protocol) encode(frame flipper.ScreenFrame, scale int, fg, bg color.Color, columns, rows int) string {
+	data := encodePNG(frame, scale, fg, bg)
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=0:%s\a",
+		len(data), columns, rows, base64.StdEncoding.EncodeToString(data))
+}
@@ -0,0 +1,49 @@
+package flipperui
+
+import "testing"
+
+func TestImageRendererSize(t *testing.T) {
+	for _, tc := range []struct {
+		protocol      imageProtocol
+		scale         int
+		width, height int
+	}{
+		// sixel rounds up, so the image never overflows its space with the smallest cells
+		{sixelProtocol{}, 1, 22, 6},
+		{sixelProtocol{}, 4, 86, 22},
+		{sixelProtocol{}, 6, 128, 32},
+		{kittyProtocol{}, 1, 16, 4},
+		{kittyProtocol{}, 4, 64, 16},
+		{iterm2Protocol{}, 2, 32, 8},
+		{iterm2Protocol{}, 4, 64, 16},
+	} {
+		r := newImageRenderer(tc.protocol)
+		if w, h := r.Size(tc.scale); w != tc.width || h != tc.height {
+			t.Errorf("%s at scale %d: got %dx%d, want %dx%d", r.Name(), tc.scale, w, h, tc.width, tc.height)
+		}
+	}
+}
+
+func TestDetectRenderer(t *testing.T) {
+	for _, tc := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, RendererHalfBlock},
+		{map[string]string{"TERM": "xterm-kitty"}, RendererKitty},
+		{map[string]string{"TERM_PROGRAM": "ghostty"}, RendererKitty},
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, RendererITerm2},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, RendererITerm2},
+		{map[string]string{"TERM": "foot"}, RendererSixel},
+		// multiplexers don't pass the images through
+		{map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux-1000/default,1,0"}, RendererHalfBlock},
+		{map[string]string{"TERM": "screen-256color", "KONSOLE_VERSION": "230804"}, RendererHalfBlock},
+		{map[string]string{"LANG": "C"}, RendererASCII},
+		{map[string]string{"LANG": "C", "LC_ALL": "en_US.UTF-8"}, RendererHalfBlock},
+		{map[string]string{"LC_CTYPE": "de_CH.utf8"}, RendererHalfBlock},
+	} {
+		if got := DetectRenderer(func(key string) string { return tc.env[key] }); got != tc.want {
+			t.Errorf("%v: got %s, want %s", tc.env, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package flipperui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const (
+	// infoRefresh is the interval in which the info panel is refreshed.
+	infoRefresh = 30 * time.Second
+	// infoTimeout is the maximum time a refresh may take.
+	infoTimeout = 10 * time.Second
+	// infoWidth is the width of the info panel.
+	infoWidth = 44
+)
+
+// storageNames are the names shown for the storages of the flipper.
+var storageNames = map[string]string{
+	"/ext": "sd card",
+	"/int": "internal",
+}
+
+type (
+	// infoMsg is sent when the info was queried.
+	infoMsg struct {
+		id   int
+		info *recfz.Info
+		err  error
+	}
+
+	// infoTickMsg triggers a refresh of the info panel.
+	infoTickMsg struct {
+		id int
+	}
+)
+
+// InfoPanel shows the device info and the free space of the flipper. It refreshes itself periodically.
+// The info is queried over rpc, which doesn't interrupt the screen stream.
+// It also implements the bubbletea.Model interface.
+type InfoPanel struct {
+	fz      *recfz.FlipperZero
+	id      int
+	info    *recfz.Info
+	err     error
+	updated time.Time
+	bar     progress.Model
+	styles  styles
+}
+
+var _ tea.Model = (*InfoPanel)(nil)
+
+// NewInfoPanel constructs a new info panel.
+func NewInfoPanel(fz *recfz.FlipperZero) tea.Model {
+	return &InfoPanel{
+		fz:     fz,
+		id:     nextID(),
+		bar:    progress.New(progress.WithDefaultGradient(), progress.WithWidth(16), progress.WithoutPercentage()),
+		styles: newStyles(DefaultTheme),
+	}
+}
+
+// Init is the bubbletea init function. It queries the info for the first time.
+func (p InfoPanel) Init() tea.Cmd {
+	return p.query()
+}
+
+// Update is the bubbletea update function and handles all tea.Msgs.
+func (p InfoPanel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ThemeMsg:
+		p.styles = newStyles(msg.Theme)
+
+	case infoMsg:
+		if msg.id != p.id {
+			return p, nil
+		}
+		p.err = msg.err
+		if msg.err == nil {
+			p.info = msg.info
+			p.updated = time.Now()
+		}
+		id := p.id
+		return p, tea.Tick(infoRefresh, func(time.Time) tea.Msg { return infoTickMsg{id: id} })
+
+	case infoTickMsg:
+		if msg.id != p.id {
+			return p, nil
+		}
+		return p, p.query()
+	}
+	return p, nil
+}
+
+// query queries the info in the background.
+func (p InfoPanel) query() tea.Cmd {
+	id, fz := p.id, p.fz
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+		defer cancel()
+		info, err := fz.Info(ctx)
+		return infoMsg{id: id, info: info, err: err}
+	}
+}
+
+// View renders the info panel.
+func (p InfoPanel) View() string {
+	if p.info == nil {
+		s := p.styles.dim.Render("loading…")
+		if p.err != nil {
+			s = p.styles.err.Render(p.err.Error())
+		}
+		return lipgloss.NewStyle().Width(infoWidth).Render(s)
+	}
+
+	var b strings.Builder
+	b.WriteString(p.styles.title.Render("ℹ " + p.info.Name()))
+	b.WriteString("\n\n")
+	row := func(key, value string) {
+		fmt.Fprintf(&b, "%-10s %s\n", key, value)
+	}
+	row("model", p.info.Properties["hardware_model"])
+	row("uid", p.info.Properties["hardware_uid"])
+	row("firmware", p.info.Firmware())
+	row("built", p.info.Properties["firmware_build_date"])
+	row("protobuf", p.info.Protobuf())
+	b.WriteString("\n")
+	for _, s := range p.info.Storage {
+		name := storageNames[s.Path]
+		if s.Err != "" || s.Total == 0 {
+			row(name, p.styles.dim.Render("not available"))
+			continue
+		}
+		used := float64(s.Total-s.Free) / float64(s.Total)
+		row(name, p.bar.ViewAs(used)+" "+recfz.FormatSize(int64(s.Free))+" free")
+	}
+	if p.info.Power != nil {
+		row("battery", p.info.Power.String())
+	} else {
+		row("battery", p.styles.dim.Render("not available"))
+	}
+	b.WriteString("\n")
+
+	if p.err != nil {
+		b.WriteString(p.styles.err.Render("refresh failed: " + p.err.Error()))
+	} else {
+		b.WriteString(p.styles.dim.Render("updated " + p.updated.Format("15:04:05")))
+	}
+	return lipgloss.NewStyle().Width(infoWidth).MaxWidth(infoWidth).Render(b.String())
+}
@@ -0,0 +1,216 @@
+package flipperui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const (
+	// launcherWidth is the width of the launcher popup, including the border.
+	launcherWidth = 40
+	// launcherTimeout is the maximum time listing the installed apps or starting an app may take.
+	launcherTimeout = 20 * time.Second
+)
+
+type (
+	// launcherAppsMsg is sent when the installed apps were listed.
+	launcherAppsMsg struct {
+		id   int
+		apps []string
+		err  error
+	}
+
+	// launcherStartMsg is sent when an app was started.
+	launcherStartMsg struct {
+		id  int
+		app string
+		err error
+	}
+)
+
+// Launcher is a popup listing the built-in and installed apps, which starts the selected app on the flipper.
+// It also implements the bubbletea.Model interface.
+type Launcher struct {
+	fz *recfz.FlipperZero
+	// id identifies the launcher, so that multiple launchers can be used in the same program
+	id        int
+	installed []string
+	// listed is false while the installed apps are being listed
+	listed bool
+	cursor int
+	offset int
+	height int
+	// exitFirst exits the running app before starting the selected one
+	exitFirst bool
+	status    string
+	err       error
+	styles    styles
+}
+
+var _ tea.Model = (*Launcher)(nil)
+
+// NewLauncher constructs a new launcher.
+func NewLauncher(fz *recfz.FlipperZero) tea.Model {
+	return &Launcher{
+		fz:     fz,
+		id:     nextID(),
+		height: flipperScreenHeight,
+		styles: newStyles(DefaultTheme),
+	}
+}
+
+// Init is the bubbletea init function. It lists the installed apps.
+func (l Launcher) Init() tea.Cmd {
+	return l.listApps()
+}
+
+// Update is the bubbletea update function and handles all tea.Msgs.
+func (l Launcher) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.height = msg.Height
+		l.scroll()
+
+	case ThemeMsg:
+		l.styles = newStyles(msg.Theme)
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			l.cursor--
+			l.scroll()
+		case "down", "j":
+			l.cursor++
+			l.scroll()
+		case "e":
+			l.exitFirst = !l.exitFirst
+		case "ctrl+r":
+			l.listed = false
+			return l, l.listApps()
+		case "enter":
+			apps := l.apps()
+			if len(apps) == 0 {
+				return l, nil
+			}
+			app := apps[l.cursor]
+			l.status, l.err = "starting "+recfz.AppName(app)+"…", nil
+			return l, l.start(app)
+		}
+
+	case launcherAppsMsg:
+		if msg.id != l.id {
+			return l, nil
+		}
+		l.listed = true
+		l.installed, l.err = msg.apps, msg.err
+		l.scroll()
+
+	case launcherStartMsg:
+		if msg.id != l.id {
+			return l, nil
+		}
+		l.status, l.err = "started "+recfz.AppName(msg.app), msg.err
+		if errors.Is(msg.err, recfz.ErrAppLocked) && !l.exitFirst {
+			l.err = fmt.Errorf("%w, press e to exit it first", msg.err)
+		}
+	}
+	return l, nil
+}
+
+// apps returns the built-in apps followed by the installed apps.
+func (l Launcher) apps() []string {
+	return append(append([]string(nil), recfz.BuiltinApps...), l.installed...)
+}
+
+// listApps lists the installed apps in the background.
+func (l Launcher) listApps() tea.Cmd {
+	id, fz := l.id, l.fz
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), launcherTimeout)
+		defer cancel()
+		apps, err := fz.InstalledApps(ctx)
+		return launcherAppsMsg{id: id, apps: apps, err: err}
+	}
+}
+
+// start starts an app in the background, exiting the running app first if enabled.
+func (l Launcher) start(app string) tea.Cmd {
+	id, fz, exitFirst := l.id, l.fz, l.exitFirst
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), launcherTimeout)
+		defer cancel()
+		if exitFirst {
+			if err := fz.ExitApp(ctx); err != nil {
+				return launcherStartMsg{id: id, app: app, err: fmt.Errorf("failed to exit the running app: %w", err)}
+			}
+		}
+		return launcherStartMsg{id: id, app: app, err: fz.StartApp(ctx, app, "")}
+	}
+}
+
+// listHeight is the number of apps that fit into the popup.
+func (l Launcher) listHeight() int {
+	// border, title, blank line, option and status line
+	return max(1, l.height-6)
+}
+
+// scroll keeps the cursor inside the list and visible.
+func (l *Launcher) scroll() {
+	l.cursor = max(0, min(l.cursor, len(l.apps())-1))
+	h := l.listHeight()
+	if l.cursor < l.offset {
+		l.offset = l.cursor
+	}
+	if l.cursor >= l.offset+h {
+		l.offset = l.cursor - h + 1
+	}
+}
+
+// View renders the popup.
+func (l Launcher) View() string {
+	// the border and the padding take four columns
+	width := launcherWidth - 4
+	apps := l.apps()
+	lines := []string{l.styles.title.Render("🚀 launch app"), ""}
+	for i := l.offset; i < len(apps) && i < l.offset+l.listHeight(); i++ {
+		cursor := " "
+		if i == l.cursor {
+			cursor = l.styles.cursor.Render("›")
+		}
+		// installed apps are shown with their category, e.g. Games
+		name := recfz.AppName(apps[i])
+		if category := strings.Trim(strings.TrimPrefix(path.Dir(apps[i]), recfz.AppsDir), "/"); name != apps[i] && category != "" {
+			name += " " + l.styles.dim.Render(category)
+		}
+		lines = append(lines, cursor+" "+name)
+	}
+	option := "[ ] exit running app first (e)"
+	if l.exitFirst {
+		option = "[x] exit running app first (e)"
+	}
+	lines = append(lines, l.styles.dim.Render(option))
+
+	var status string
+	switch {
+	case l.err != nil:
+		status = l.styles.err.Render(l.err.Error())
+	case l.status != "":
+		status = l.status
+	case !l.listed:
+		status = l.styles.dim.Render("listing installed apps…")
+	default:
+		status = l.styles.dim.Render("enter start • ctrl+r reload • esc close")
+	}
+	lines = append(lines, status)
+
+	content := lipgloss.NewStyle().Width(width).MaxWidth(width).Render(strings.Join(lines, "\n"))
+	return l.styles.popupBorder.Render(content)
+}
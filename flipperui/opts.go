@@ -1,5 +1,7 @@
 package flipperui
 
+import "github.com/jon4hz/fztea/recfz"
+
 // FlipperOpts represents an optional configuration for the flipper model.
 type FlipperOpts func(*Model)
 
@@ -11,16 +13,80 @@ func WithScreenshotResolution(width, height int) FlipperOpts {
 	}
 }
 
-// WithFgColor sets the foreground color of the flipper screen.
+// WithTheme sets the theme of the screen and everything around it. It's sent to the other models with a ThemeMsg.
+func WithTheme(theme Theme) FlipperOpts {
+	return func(m *Model) {
+		m.theme = theme
+		m.themeSet = true
+	}
+}
+
+// WithThemes sets the themes that are cycled through with ctrl+o, by default the built-in Themes.
+func WithThemes(themes ...Theme) FlipperOpts {
+	return func(m *Model) {
+		m.themes = themes
+	}
+}
+
+// WithFgColor sets the foreground color of the flipper screen, overriding the one of the theme.
 func WithFgColor(color string) FlipperOpts {
 	return func(m *Model) {
-		m.fgColor = color
+		m.theme.Fg = color
 	}
 }
 
-// WithBgColor sets the background color of the flipper screen.
+// WithBgColor sets the background color of the flipper screen, overriding the one of the theme.
 func WithBgColor(color string) FlipperOpts {
 	return func(m *Model) {
-		m.bgColor = color
+		m.theme.Bg = color
+	}
+}
+
+// WithInputAllowed sets a function which decides if input events are forwarded to the flipper.
+// This can be used to make the flipper model read-only, e.g. for spectators.
+func WithInputAllowed(fn func() bool) FlipperOpts {
+	return func(m *Model) {
+		m.inputAllowed = fn
+	}
+}
+
+// WithStateUpdates sets a channel on which the model receives the connection state of the flipper.
+// While the flipper is not connected, the model renders the state instead of the last screen.
+func WithStateUpdates(events <-chan recfz.Event) FlipperOpts {
+	return func(m *Model) {
+		m.stateUpdate = events
+	}
+}
+
+// WithStatusBar shows a status bar below the screen, e.g. with the connection state and the frame rate.
+// The status bar is hidden if the terminal is too short.
+func WithStatusBar() FlipperOpts {
+	return func(m *Model) {
+		m.statusBarEnabled = true
+	}
+}
+
+// WithSkin draws the body of the flipper around the screen, with buttons that can be clicked with the mouse.
+// Mouse events must be relative to the top left corner of the model's view.
+func WithSkin() FlipperOpts {
+	return func(m *Model) {
+		m.skin.enabled = true
+	}
+}
+
+// WithRenderers sets the renderers drawing the screen, e.g. with sixel graphics. See NewRenderers.
+// If the screen is fit into the terminal, the first renderer showing the whole screen is used.
+// Without renderers, the half block renderer is used.
+func WithRenderers(renderers ...Renderer) FlipperOpts {
+	return func(m *Model) {
+		m.renderers = renderers
+	}
+}
+
+// WithScale sets the scale the screen is drawn at.
+// If it's not set, the renderer and the scale are picked to fit the screen into the terminal.
+func WithScale(scale int) FlipperOpts {
+	return func(m *Model) {
+		m.scale = scale
 	}
 }
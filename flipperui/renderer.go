@@ -0,0 +1,173 @@
+package flipperui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/flipperdevices/go-flipper"
+)
+
+const (
+	// flipperPixelWidth and flipperPixelHeight are the resolution of the flipper screen.
+	flipperPixelWidth  = 128
+	flipperPixelHeight = 64
+)
+
+// names of the renderers, see NewRenderer.
+const (
+	RendererAuto      = "auto"
+	RendererHalfBlock = "halfblock"
+	RendererBraille   = "braille"
+	RendererSextant   = "sextant"
+	RendererASCII     = "ascii"
+	RendererSixel     = "sixel"
+	RendererKitty     = "kitty"
+	RendererITerm2    = "iterm2"
+)
+
+// Renderers are the names of the renderers that can be passed to NewRenderer.
+var Renderers = []string{
+	RendererAuto, RendererHalfBlock, RendererBraille, RendererSextant, RendererASCII, RendererSixel, RendererKitty, RendererITerm2,
+}
+
+// Renderer draws a frame of the flipper screen in the terminal.
+type Renderer interface {
+	// Name returns the name of the renderer, e.g. halfblock.
+	Name() string
+	// DefaultScale returns the scale used if none is set.
+	DefaultScale() int
+	// Size returns the number of columns and rows a frame takes at the given scale.
+	Size(scale int) (width, height int)
+	// Render renders a frame at the given scale with the given colors.
+	Render(frame flipper.ScreenFrame, scale int, fg, bg string) string
+}
+
+// NewRenderer returns the renderer with the given name.
+// auto detects the best renderer supported by the terminal using getenv, see DetectRenderer.
+func NewRenderer(name string, getenv func(string) string) (Renderer, error) {
+	if name == RendererAuto {
+		name = DetectRenderer(getenv)
+	}
+	switch name {
+	case RendererHalfBlock:
+		return halfBlockRenderer, nil
+	case RendererBraille:
+		return brailleRenderer, nil
+	case RendererSextant:
+		return sextantRenderer, nil
+	case RendererASCII:
+		return asciiRenderer, nil
+	case RendererSixel:
+		return newImageRenderer(sixelProtocol{}), nil
+	case RendererKitty:
+		return newImageRenderer(kittyProtocol{id: nextID()}), nil
+	case RendererITerm2:
+		return newImageRenderer(iterm2Protocol{}), nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q, must be one of %s", name, strings.Join(Renderers, ", "))
+}
+
+// NewRenderers returns the renderers a model picks from to fit the screen into the terminal, the largest first.
+// auto adds the braille renderer after the half block renderer for small terminals,
+// otherwise only the named renderer is returned and the screen is only scaled.
+func NewRenderers(name string, getenv func(string) string) ([]Renderer, error) {
+	r, err := NewRenderer(name, getenv)
+	if err != nil {
+		return nil, err
+	}
+	renderers := []Renderer{r}
+	if name == RendererAuto && r.Name() == RendererHalfBlock {
+		renderers = append(renderers, brailleRenderer)
+	}
+	return renderers, nil
+}
+
+// DetectRenderer returns the name of the best renderer supported by the terminal.
+// The terminal is detected by its environment variables, which works over ssh as well,
+// as long as the client passes them. Terminals without graphics support use the half block renderer,
+// or the ascii renderer if the locale isn't utf-8.
+func DetectRenderer(getenv func(string) string) string {
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		// multiplexers don't pass the images through
+	case getenv("KITTY_WINDOW_ID") != "" || strings.Contains(term, "kitty") || strings.Contains(term, "ghostty") || program == "ghostty":
+		return RendererKitty
+	case program == "iTerm.app" || program == "WezTerm" || getenv("LC_TERMINAL") == "iTerm2":
+		return RendererITerm2
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel") || getenv("KONSOLE_VERSION") != "":
+		return RendererSixel
+	}
+	if !unicodeLocale(getenv) {
+		return RendererASCII
+	}
+	return RendererHalfBlock
+}
+
+// unicodeLocale returns false if the locale is set and doesn't use utf-8, e.g. LANG=C.
+// If no locale is set, which is common over ssh, it assumes the terminal supports unicode.
+func unicodeLocale(getenv func(string) string) bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := strings.ToLower(getenv(key)); v != "" {
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
+
+// pixelSet returns true if the pixel of the frame scaled up by scale is set.
+func pixelSet(frame flipper.ScreenFrame, x, y, scale int) bool {
+	return frame.IsPixelSet(x/scale, y/scale)
+}
+
+// glyphRenderer draws a block of pixels per cell with a glyph, e.g. two pixels with a half block.
+// At scale n, every pixel of the flipper is drawn as nxn pixels.
+type glyphRenderer struct {
+	name string
+	// cellWidth and cellHeight are the number of pixels drawn per cell
+	cellWidth, cellHeight int
+	// glyph returns the glyph of a cell. The bits are the pixels of the cell row by row, starting with the top left pixel as bit 0.
+	glyph func(bits int) rune
+}
+
+// halfBlockRenderer draws 1x2 pixels per cell with half blocks. At scale 1, the screen takes 128x32 cells.
+var halfBlockRenderer = glyphRenderer{
+	name:      RendererHalfBlock,
+	cellWidth: 1, cellHeight: 2,
+	glyph: func(bits int) rune {
+		return []rune{' ', upperHalfBlock, lowerHalfBlock, fullBlock}[bits]
+	},
+}
+
+func (r glyphRenderer) Name() string { return r.name }
+
+func (r glyphRenderer) DefaultScale() int { return 1 }
+
+func (r glyphRenderer) Size(scale int) (int, int) {
+	return (flipperPixelWidth*scale + r.cellWidth - 1) / r.cellWidth, (flipperPixelHeight*scale + r.cellHeight - 1) / r.cellHeight
+}
+
+func (r glyphRenderer) Render(frame flipper.ScreenFrame, scale int, fg, bg string) string {
+	width, height := r.Size(scale)
+	var s strings.Builder
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			var bits int
+			for dy := 0; dy < r.cellHeight; dy++ {
+				for dx := 0; dx < r.cellWidth; dx++ {
+					x, y := col*r.cellWidth+dx, row*r.cellHeight+dy
+					if x < flipperPixelWidth*scale && y < flipperPixelHeight*scale && pixelSet(frame, x, y, scale) {
+						bits |= 1 << (dy*r.cellWidth + dx)
+					}
+				}
+			}
+			s.WriteRune(r.glyph(bits))
+		}
+		// if not last line
+		if row < height-1 {
+			s.WriteRune('\n')
+		}
+	}
+	return lipgloss.NewStyle().Background(lipgloss.Color(bg)).Foreground(lipgloss.Color(fg)).Render(s.String())
+}
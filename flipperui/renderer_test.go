@@ -0,0 +1,74 @@
+package flipperui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewRenderers(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		// auto falls back to braille in small terminals
+		{RendererAuto, map[string]string{}, []string{RendererHalfBlock, RendererBraille}},
+		{RendererAuto, map[string]string{"TERM": "xterm-kitty"}, []string{RendererKitty}},
+		{RendererHalfBlock, map[string]string{}, []string{RendererHalfBlock}},
+		{RendererSextant, map[string]string{}, []string{RendererSextant}},
+	} {
+		renderers, err := NewRenderers(tc.name, func(key string) string { return tc.env[key] })
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range renderers {
+			got = append(got, r.Name())
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s with %v: got %v, want %v", tc.name, tc.env, got, tc.want)
+		}
+	}
+
+	if _, err := NewRenderers("vt100", func(string) string { return "" }); err == nil {
+		t.Fatal("expected an error for an unknown renderer")
+	}
+}
+
+func TestFitScreen(t *testing.T) {
+	sixel := newImageRenderer(sixelProtocol{})
+	for _, tc := range []struct {
+		renderers     []Renderer
+		width, height int
+		want          string
+		scale         int
+	}{
+		{[]Renderer{halfBlockRenderer, brailleRenderer}, 128, 32, RendererHalfBlock, 1},
+		{[]Renderer{halfBlockRenderer, brailleRenderer}, 300, 70, RendererHalfBlock, 2},
+		// the half block renderer doesn't fit, braille does
+		{[]Renderer{halfBlockRenderer, brailleRenderer}, 127, 32, RendererBraille, 1},
+		{[]Renderer{halfBlockRenderer, brailleRenderer}, 128, 31, RendererBraille, 1},
+		// nothing fits, the last renderer is cropped
+		{[]Renderer{halfBlockRenderer, brailleRenderer}, 40, 10, RendererBraille, 1},
+		{[]Renderer{sixel}, 100, 30, RendererSixel, 4},
+		// the scale is capped
+		{[]Renderer{halfBlockRenderer}, 10000, 10000, RendererHalfBlock, maxScale},
+	} {
+		m := New(nil, nil, WithRenderers(tc.renderers...)).(*Model)
+		m.fitScreen(tc.width, tc.height)
+		if m.renderer.Name() != tc.want || m.scale != tc.scale {
+			t.Errorf("%dx%d: got %s at scale %d, want %s at scale %d", tc.width, tc.height, m.renderer.Name(), m.scale, tc.want, tc.scale)
+		}
+	}
+}
+
+func TestNoRenderers(t *testing.T) {
+	m := New(nil, nil, WithRenderers()).(*Model)
+	if m.renderer.Name() != RendererHalfBlock {
+		t.Fatalf("expected the half block renderer, got %s", m.renderer.Name())
+	}
+	m.fitScreen(40, 10)
+	if m.renderer.Name() != RendererHalfBlock || m.scale != 1 {
+		t.Fatalf("expected the half block renderer at scale 1, got %s at scale %d", m.renderer.Name(), m.scale)
+	}
+}
@@ -0,0 +1,155 @@
+package flipperui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/flipperdevices/go-flipper"
+)
+
+const (
+	// skinButtonWidth and skinButtonHeight are the size of a button, including the border.
+	skinButtonWidth  = 7
+	skinButtonHeight = 3
+	// skinGap is the space between the screen and the buttons.
+	skinGap = 2
+	// skinWidth and skinHeight are the space the skin takes in addition to the screen:
+	// the border and padding of the body, the gap and three columns of buttons.
+	skinWidth  = 4 + skinGap + 3*skinButtonWidth
+	skinHeight = 2
+
+	// skinLitDuration is how long a button lights up when it's pressed with the keyboard.
+	skinLitDuration = 150 * time.Millisecond
+	// longPressDuration is how long a click must be held to send a long press.
+	longPressDuration = 500 * time.Millisecond
+)
+
+// skinLayout are the buttons of the skin, row by row like the d-pad of the flipper, with back in the bottom right corner.
+// -1 leaves a cell empty.
+var skinLayout = [3][3]flipper.InputKey{
+	{-1, flipper.InputKeyUp, -1},
+	{flipper.InputKeyLeft, flipper.InputKeyOk, flipper.InputKeyRight},
+	{-1, flipper.InputKeyDown, flipper.InputKeyBack},
+}
+
+// skinLabels are the labels of the buttons.
+var skinLabels = map[flipper.InputKey]string{
+	flipper.InputKeyUp:    "▲",
+	flipper.InputKeyDown:  "▼",
+	flipper.InputKeyLeft:  "◀",
+	flipper.InputKeyRight: "▶",
+	flipper.InputKeyOk:    "OK",
+	flipper.InputKeyBack:  "↩",
+}
+
+type (
+	// skinReleaseMsg turns off a button lit by a key press.
+	skinReleaseMsg struct {
+		id  int
+		seq int
+	}
+
+	// skinHoldMsg is sent when a click was held long enough for a long press.
+	skinHoldMsg struct {
+		id  int
+		seq int
+	}
+)
+
+// skin draws the body of the flipper around the screen, with buttons that can be clicked.
+type skin struct {
+	enabled bool
+	// lit is the button drawn pressed, -1 if none
+	lit flipper.InputKey
+	// seq identifies the last press, so that the ticks of older presses are ignored
+	seq int
+	// clicked is true while the mouse button is held on a button
+	clicked bool
+	// long is true if the held click already sent a long press
+	long bool
+}
+
+// light lights up a button pressed with the keyboard for skinLitDuration.
+func (m *Model) light(key flipper.InputKey) tea.Cmd {
+	m.skin.seq++
+	m.skin.lit, m.skin.clicked = key, false
+	id, seq := m.id, m.skin.seq
+	return tea.Tick(skinLitDuration, func(time.Time) tea.Msg { return skinReleaseMsg{id: id, seq: seq} })
+}
+
+// click handles the mouse on the buttons. A click sends a short press on release,
+// a click held for longPressDuration sends a long press instead.
+func (m *Model) click(msg tea.MouseMsg) tea.Cmd {
+	if msg.Button != tea.MouseButtonLeft {
+		return nil
+	}
+	switch msg.Action {
+	case tea.MouseActionPress:
+		key := m.skinButton(msg.X, msg.Y)
+		if key == -1 {
+			return nil
+		}
+		m.skin.seq++
+		m.skin.lit, m.skin.clicked, m.skin.long = key, true, false
+		id, seq := m.id, m.skin.seq
+		return tea.Tick(longPressDuration, func(time.Time) tea.Msg { return skinHoldMsg{id: id, seq: seq} })
+	case tea.MouseActionRelease:
+		if !m.skin.clicked {
+			return nil
+		}
+		if !m.skin.long {
+			m.sendFlipperEvent(m.skin.lit, false)
+		}
+		m.skin.lit, m.skin.clicked = -1, false
+	}
+	return nil
+}
+
+// hold sends a long press if the click is still held.
+func (m *Model) hold(msg skinHoldMsg) {
+	if msg.seq != m.skin.seq || !m.skin.clicked {
+		return
+	}
+	m.skin.long = true
+	m.sendFlipperEvent(m.skin.lit, true)
+}
+
+// skinButton returns the button at the given position relative to the view, or -1 if there is none.
+func (m Model) skinButton(x, y int) flipper.InputKey {
+	// the buttons start after the border and padding of the body, the screen and the gap
+	x -= 2 + m.viewport.Width + skinGap
+	y -= 1 + m.skinButtonsOffset()
+	if x < 0 || y < 0 || x >= 3*skinButtonWidth || y >= 3*skinButtonHeight {
+		return -1
+	}
+	return skinLayout[y/skinButtonHeight][x/skinButtonWidth]
+}
+
+// skinButtonsOffset returns the number of rows above the buttons, which are centered next to the screen.
+func (m Model) skinButtonsOffset() int {
+	return max(0, (m.viewport.Height-3*skinButtonHeight)/2)
+}
+
+// skinView draws the body around the screen.
+func (m Model) skinView(screen string) string {
+	rows := make([]string, len(skinLayout))
+	for i, row := range skinLayout {
+		buttons := make([]string, len(row))
+		for j, key := range row {
+			switch {
+			case key == -1:
+				buttons[j] = lipgloss.NewStyle().Width(skinButtonWidth).Height(skinButtonHeight).Render("")
+			case key == m.skin.lit:
+				buttons[j] = m.styles.skinLit.Render(skinLabels[key])
+			default:
+				buttons[j] = m.styles.skinButton.Render(skinLabels[key])
+			}
+		}
+		rows[i] = lipgloss.JoinHorizontal(lipgloss.Top, buttons...)
+	}
+	// the error message may be narrower than the screen, the buttons stay in place
+	screen = lipgloss.PlaceHorizontal(m.viewport.Width, lipgloss.Left, screen)
+	buttons := lipgloss.NewStyle().MarginTop(m.skinButtonsOffset()).MarginLeft(skinGap).Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+	return m.styles.skinBody.Render(lipgloss.JoinHorizontal(lipgloss.Top, screen, buttons))
+}
@@ -0,0 +1,172 @@
+package flipperui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+const (
+	// statusBarHeight is the number of rows of the status bar.
+	statusBarHeight = 1
+	// frameRateWindow is the time span in which the frames are counted for the frame rate.
+	frameRateWindow = time.Second
+	// statusTimeout is the maximum time to query the name or the battery of the flipper.
+	statusTimeout = 10 * time.Second
+	// powerRefresh is the interval in which the battery is queried.
+	powerRefresh = 30 * time.Second
+)
+
+type (
+	// nameMsg is sent when the name of the flipper was queried.
+	nameMsg struct {
+		id   int
+		name string
+	}
+
+	// statusTickMsg refreshes the status bar, so the frame rate drops to 0 if no frames arrive.
+	statusTickMsg struct {
+		id int
+	}
+
+	// powerMsg is sent when the battery was queried. power is nil if it couldn't be queried.
+	powerMsg struct {
+		id    int
+		power *recfz.Power
+	}
+
+	// powerTickMsg triggers a query of the battery.
+	powerTickMsg struct {
+		id int
+	}
+)
+
+// statusBar keeps track of the values shown in the status bar.
+type statusBar struct {
+	// frames holds the arrival times of the frames within the last frameRateWindow
+	frames []time.Time
+	// latency is the time the last input event took to be acknowledged by the flipper
+	latency time.Duration
+	// lastAction describes the last thing the user did, e.g. taking a screenshot
+	lastAction string
+	name       string
+	// power is the battery status, nil if it's unknown
+	power *recfz.Power
+}
+
+// frame records the arrival of a screen frame.
+func (s *statusBar) frame(t time.Time) {
+	s.frames = append(s.frames, t)
+	for len(s.frames) > 0 && t.Sub(s.frames[0]) > frameRateWindow {
+		s.frames = s.frames[1:]
+	}
+}
+
+// frameRate returns the number of frames received within the last frameRateWindow.
+func (s *statusBar) frameRate() int {
+	n := 0
+	for _, t := range s.frames {
+		if time.Since(t) <= frameRateWindow {
+			n++
+		}
+	}
+	return n
+}
+
+// queryName queries the name of the flipper in the background.
+func queryName(id int, fz *recfz.FlipperZero) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		defer cancel()
+		info, err := fz.DeviceInfo(ctx)
+		if err != nil {
+			return nil
+		}
+		return nameMsg{id: id, name: info["hardware_name"]}
+	}
+}
+
+// queryPower queries the battery in the background.
+func queryPower(id int, fz *recfz.FlipperZero) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		defer cancel()
+		power, _ := fz.PowerInfo(ctx)
+		return powerMsg{id: id, power: power}
+	}
+}
+
+// powerTick queries the battery again after powerRefresh.
+func powerTick(id int) tea.Cmd {
+	return tea.Tick(powerRefresh, func(time.Time) tea.Msg { return powerTickMsg{id: id} })
+}
+
+// statusTick refreshes the status bar after frameRateWindow.
+func statusTick(id int) tea.Cmd {
+	return tea.Tick(frameRateWindow, func(time.Time) tea.Msg { return statusTickMsg{id: id} })
+}
+
+// showStatusBar returns true if the status bar is enabled and fits below the screen.
+func (m Model) showStatusBar() bool {
+	height := m.viewport.Height + statusBarHeight
+	if m.skin.enabled {
+		height += skinHeight
+	}
+	return m.statusBarEnabled && (m.height == 0 || m.height >= height)
+}
+
+// statusBarView renders the status bar.
+func (m Model) statusBarView() string {
+	state := m.state.State
+	if m.stateUpdate == nil {
+		state = recfz.StateConnected
+		if _, err := m.fz.GetFlipper(); err != nil {
+			state = recfz.StateDisconnected
+		}
+	}
+	indicator := "○"
+	if state == recfz.StateConnected {
+		indicator = "●"
+	}
+
+	name := m.status.name
+	if name == "" {
+		name = "…"
+	}
+	latency := "-"
+	switch {
+	case m.status.latency >= time.Millisecond:
+		latency = fmt.Sprintf("%dms", m.status.latency.Milliseconds())
+	case m.status.latency > 0:
+		latency = "<1ms"
+	}
+	parts := []string{
+		indicator + " " + state.String(),
+		m.fz.Port(),
+		name,
+	}
+	// the battery is left out if the firmware doesn't report it
+	if p := m.status.power; p != nil {
+		icon := "🔋"
+		if p.Charging() {
+			icon = "⚡"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d%%", icon, p.Charge))
+	}
+	parts = append(parts,
+		fmt.Sprintf("%d fps", m.status.frameRate()),
+		fmt.Sprintf("%s %dx", m.renderer.Name(), m.scale),
+		"input "+latency,
+	)
+	if m.status.lastAction != "" {
+		parts = append(parts, m.status.lastAction)
+	}
+	return m.styles.status.
+		Width(m.viewport.Width).
+		MaxWidth(m.viewport.Width).
+		Render(" " + strings.Join(parts, " │ "))
+}
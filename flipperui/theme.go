@@ -0,0 +1,224 @@
+package flipperui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// themeExt is the extension of theme files.
+const themeExt = ".json"
+
+// Theme is a color scheme for the screen and everything around it. Colors are hex strings, e.g. #FF8C00.
+type Theme struct {
+	Name string `json:"name"`
+	// Fg and Bg are the colors of the screen. An empty Bg keeps the background of the terminal.
+	Fg string `json:"fg"`
+	Bg string `json:"bg"`
+	// Accent highlights e.g. the cursor, pressed buttons and the focused tile.
+	Accent string `json:"accent"`
+	// Muted is the color of hints, labels and popup borders.
+	Muted string `json:"muted"`
+	// Frame is the color of the body of the skin and the borders of the dashboard tiles.
+	Frame string `json:"frame"`
+	// Dir is the color of the folders in the file browser.
+	Dir      string `json:"dir"`
+	StatusFg string `json:"status_fg"`
+	StatusBg string `json:"status_bg"`
+	Error    string `json:"error"`
+}
+
+// DefaultTheme is the classic orange backlight of the flipper.
+var DefaultTheme = Theme{
+	Name:     "classic",
+	Fg:       defaultFgColor,
+	Bg:       defaultBgColor,
+	Accent:   defaultBgColor,
+	Muted:    "#808080",
+	Frame:    "#606060",
+	Dir:      "#5FAFFF",
+	StatusFg: "#C0C0C0",
+	StatusBg: "#303030",
+	Error:    "#FF0000",
+}
+
+// ErrStyle is the style of the error message in the default theme.
+//
+// Deprecated: the models derive their styles from the theme, use Theme.Error instead.
+var ErrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(DefaultTheme.Error))
+
+// StatusBarStyle is the style of the status bar in the default theme.
+//
+// Deprecated: the models derive their styles from the theme, use Theme.StatusFg and Theme.StatusBg instead.
+var StatusBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(DefaultTheme.StatusFg)).Background(lipgloss.Color(DefaultTheme.StatusBg))
+
+// Themes are the built-in themes.
+var Themes = []Theme{
+	DefaultTheme,
+	{
+		Name: "white", Fg: "#1C1C1C", Bg: "#E4E4E4", Accent: "#FFFFFF", Muted: "#808080", Frame: "#A8A8A8",
+		Dir: "#5FAFFF", StatusFg: "#C0C0C0", StatusBg: "#303030", Error: "#FF0000",
+	},
+	{
+		Name: "green", Fg: "#0F380F", Bg: "#8BAC0F", Accent: "#9BBC0F", Muted: "#808080", Frame: "#306230",
+		Dir: "#9BBC0F", StatusFg: "#9BBC0F", StatusBg: "#0F380F", Error: "#FF0000",
+	},
+	{
+		Name: "high-contrast", Fg: "#000000", Bg: "#FFFFFF", Accent: "#FFFF00", Muted: "#FFFFFF", Frame: "#FFFFFF",
+		Dir: "#00FFFF", StatusFg: "#000000", StatusBg: "#FFFFFF", Error: "#FF0000",
+	},
+	// the colors of the Okabe-Ito palette, which can be told apart with all common types of color blindness
+	{
+		Name: "colorblind", Fg: "#000000", Bg: "#56B4E9", Accent: "#E69F00", Muted: "#999999", Frame: "#666666",
+		Dir: "#56B4E9", StatusFg: "#FFFFFF", StatusBg: "#0072B2", Error: "#D55E00",
+	},
+	// the screen and the status bar keep the background of the terminal
+	{
+		Name: "transparent", Fg: defaultBgColor, Accent: defaultBgColor, Muted: "#808080", Frame: "#606060",
+		Dir: "#5FAFFF", StatusFg: "#808080", Error: "#FF0000",
+	},
+}
+
+// ThemeMsg changes the theme of the models next to a flipper model, e.g. the file browser.
+// The flipper model sends it when it starts with a theme set by WithTheme and when the theme is cycled.
+// Other flipper models ignore it, so each of them keeps its own theme.
+type ThemeMsg struct {
+	// id is the id of the flipper model that sent the message.
+	id int
+	Theme
+}
+
+// ThemeDir returns the directory of the user's theme files, e.g. ~/.config/fztea/themes.
+func ThemeDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fztea", "themes"), nil
+}
+
+// LoadThemes returns the built-in themes followed by the themes in the .json files of dir.
+// A theme is named after its file unless it sets a name, colors it doesn't set are taken from DefaultTheme.
+// A theme with the name of a built-in theme replaces it. A missing dir is not an error.
+func LoadThemes(dir string) ([]Theme, error) {
+	themes := append([]Theme(nil), Themes...)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return themes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != themeExt {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// unset colors keep the values of the default theme, an explicit empty bg keeps the background of the terminal
+		t := DefaultTheme
+		t.Name = strings.TrimSuffix(e.Name(), themeExt)
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("theme %s: %w", e.Name(), err)
+		}
+		if i := themeIndex(themes, t.Name); i != -1 {
+			themes[i] = t
+			continue
+		}
+		themes = append(themes, t)
+	}
+	return themes, nil
+}
+
+// FindTheme returns the theme with the given name.
+func FindTheme(themes []Theme, name string) (Theme, error) {
+	if i := themeIndex(themes, name); i != -1 {
+		return themes[i], nil
+	}
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.Name
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// themeIndex returns the index of the theme with the given name, or -1 if there is none.
+func themeIndex(themes []Theme, name string) int {
+	for i, t := range themes {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// themeColor returns the color of a hex string for images. An empty string is transparent.
+func themeColor(s string) color.Color {
+	if s == "" {
+		return color.Transparent
+	}
+	return lipgloss.Color(s)
+}
+
+// styles are the styles of the models derived from a theme.
+type styles struct {
+	// screen is the style of the screen, e.g. when the connection state is shown instead
+	screen        lipgloss.Style
+	err           lipgloss.Style
+	status        lipgloss.Style
+	title         lipgloss.Style
+	cursor        lipgloss.Style
+	dir           lipgloss.Style
+	dim           lipgloss.Style
+	previewBorder lipgloss.Style
+	popupBorder   lipgloss.Style
+	skinBody      lipgloss.Style
+	skinButton    lipgloss.Style
+	skinLit       lipgloss.Style
+}
+
+// newStyles derives the styles from a theme.
+func newStyles(t Theme) styles {
+	skinButton := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(t.Muted)).
+		Width(skinButtonWidth - 2).Align(lipgloss.Center)
+	return styles{
+		screen:        lipgloss.NewStyle().Background(lipgloss.Color(t.Bg)).Foreground(lipgloss.Color(t.Fg)),
+		err:           lipgloss.NewStyle().Foreground(lipgloss.Color(t.Error)),
+		status:        lipgloss.NewStyle().Foreground(lipgloss.Color(t.StatusFg)).Background(lipgloss.Color(t.StatusBg)),
+		title:         lipgloss.NewStyle().Bold(true),
+		cursor:        lipgloss.NewStyle().Foreground(lipgloss.Color(t.Accent)).Bold(true),
+		dir:           lipgloss.NewStyle().Foreground(lipgloss.Color(t.Dir)),
+		dim:           lipgloss.NewStyle().Foreground(lipgloss.Color(t.Muted)),
+		previewBorder: lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color(t.Muted)).PaddingLeft(1),
+		popupBorder:   lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(t.Muted)).Padding(0, 1),
+		skinBody:      lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(t.Frame)).Padding(0, 1),
+		skinButton:    skinButton,
+		skinLit:       skinButton.BorderForeground(lipgloss.Color(t.Accent)).Foreground(lipgloss.Color(t.Accent)).Bold(true),
+	}
+}
+
+// setTheme changes the theme of the screen.
+func (m *Model) setTheme(t Theme) {
+	m.theme = t
+	m.styles = newStyles(t)
+	m.render()
+}
+
+// cycleTheme switches to the next theme and tells the other models about it.
+func (m *Model) cycleTheme() tea.Cmd {
+	t := m.themes[(themeIndex(m.themes, m.theme.Name)+1)%len(m.themes)]
+	m.setTheme(t)
+	m.status.lastAction = "theme " + t.Name
+	id := m.id
+	return func() tea.Msg { return ThemeMsg{id: id, Theme: t} }
+}
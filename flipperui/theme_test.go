@@ -0,0 +1,72 @@
+package flipperui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// initMsgs runs the commands of Init and returns their messages. The screen updates must be closed.
+func initMsgs(m tea.Model) []tea.Msg {
+	var msgs []tea.Msg
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, c := range msg {
+				run(c)
+			}
+		case nil:
+		default:
+			msgs = append(msgs, msg)
+		}
+	}
+	run(m.Init())
+	return msgs
+}
+
+func closedScreen() chan ScreenMsg {
+	c := make(chan ScreenMsg)
+	close(c)
+	return c
+}
+
+func TestInitSendsTheme(t *testing.T) {
+	green := Themes[2]
+	m := New(nil, closedScreen(), WithTheme(green))
+	msgs := initMsgs(m)
+	if len(msgs) != 1 {
+		t.Fatalf("expected a single message, got %v", msgs)
+	}
+	if msg, ok := msgs[0].(ThemeMsg); !ok || msg.Name != green.Name {
+		t.Fatalf("expected the theme %s, got %v", green.Name, msgs[0])
+	}
+
+	// without a theme, the other models keep theirs
+	if msgs := initMsgs(New(nil, closedScreen())); len(msgs) != 0 {
+		t.Fatalf("expected no messages, got %v", msgs)
+	}
+}
+
+func TestThemesAreIndependent(t *testing.T) {
+	a := New(nil, closedScreen(), WithTheme(Themes[1]))
+	b := New(nil, closedScreen(), WithTheme(Themes[3]))
+
+	// the messages of a reach b as well, e.g. in the dashboard
+	for _, msg := range initMsgs(a) {
+		b, _ = b.Update(msg)
+	}
+	msg := a.(*Model).cycleTheme()()
+	a, _ = a.Update(msg)
+	b, _ = b.Update(msg)
+
+	if got := a.(Model).theme.Name; got != Themes[2].Name {
+		t.Errorf("expected a to cycle to %s, got %s", Themes[2].Name, got)
+	}
+	if got := b.(Model).theme.Name; got != Themes[3].Name {
+		t.Errorf("expected b to keep %s, got %s", Themes[3].Name, got)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var infoFlags struct {
+	json bool
+	all  bool
+}
+
+var infoCmd = &coral.Command{
+	Use:          "info",
+	Short:        "Print information about the flipper, e.g. the firmware version and the free space",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE:         info,
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoFlags.json, "json", false, "print the information as json")
+	infoCmd.Flags().BoolVarP(&infoFlags.all, "all", "a", false, "also print all device info properties")
+}
+
+func info(cmd *coral.Command, _ []string) error {
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	i, err := fz.Info(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if infoFlags.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(i)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "name\t%s\n", i.Name())
+	fmt.Fprintf(w, "model\t%s\n", i.Properties["hardware_model"])
+	fmt.Fprintf(w, "uid\t%s\n", i.Properties["hardware_uid"])
+	fmt.Fprintf(w, "firmware\t%s\n", i.Firmware())
+	fmt.Fprintf(w, "build date\t%s\n", i.Properties["firmware_build_date"])
+	fmt.Fprintf(w, "protobuf\t%s\n", i.Protobuf())
+	for _, s := range i.Storage {
+		if s.Err != "" {
+			fmt.Fprintf(w, "%s\t%s\n", s.Path, s.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s free of %s\n", s.Path, recfz.FormatSize(int64(s.Free)), recfz.FormatSize(int64(s.Total)))
+	}
+	if i.Power != nil {
+		fmt.Fprintf(w, "battery\t%s\n", i.Power)
+	} else {
+		fmt.Fprintf(w, "battery\t%s\n", i.PowerErr)
+	}
+
+	if infoFlags.all {
+		fmt.Fprintln(w)
+		keys := make([]string, 0, len(i.Properties))
+		for k := range i.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\n", k, i.Properties[k])
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,17 @@
+// Package race tells the tests if they run with the race detector.
+package race
+
+import "testing"
+
+// SkipGoFlipper skips a test talking to a flipper over go-flipper if the race detector is enabled.
+//
+// go-flipper v0.6.0 arms the timer of a call in Flipper.call after releasing its lock,
+// while Flipper.read resets the timer for every response with has_next set without taking it.
+// The race detector reports it for every response of more than one message,
+// e.g. the device info which recfz reads when it connects. It can't be worked around outside of the library.
+func SkipGoFlipper(t testing.TB) {
+	t.Helper()
+	if Enabled {
+		t.Skip("go-flipper races on the timer of its calls, see internal/race")
+	}
+}
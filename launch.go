@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var launchFlags struct {
+	args string
+	exit bool
+	list bool
+}
+
+var launchCmd = &coral.Command{
+	Use:   "launch [app]",
+	Short: "Start an app on the flipper",
+	Long: "Start an app on the flipper.\n\n" +
+		"The app is the name of a built-in app, e.g. Sub-GHz, the path of an installed .fap file\n" +
+		"or the name of an installed app, e.g. snake_game for /ext/apps/Games/snake_game.fap.",
+	Example: "  fztea launch Sub-GHz --args /ext/subghz/gate.sub\n" +
+		"  fztea launch snake_game --exit\n" +
+		"  fztea launch --list",
+	Args:         coral.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         launch,
+}
+
+func init() {
+	launchCmd.Flags().StringVar(&launchFlags.args, "args", "", "arguments passed to the app, e.g. the path of a file to open")
+	launchCmd.Flags().BoolVarP(&launchFlags.exit, "exit", "e", false, "exit the running app first")
+	launchCmd.Flags().BoolVar(&launchFlags.list, "list", false, "list the built-in and installed apps")
+}
+
+func launch(cmd *coral.Command, args []string) error {
+	if !launchFlags.list && len(args) == 0 {
+		return errors.New("no app given")
+	}
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	ctx := cmd.Context()
+
+	if launchFlags.list {
+		installed, err := fz.InstalledApps(ctx)
+		if err != nil {
+			return err
+		}
+		for _, app := range append(slices.Clone(recfz.BuiltinApps), installed...) {
+			fmt.Println(app)
+		}
+		return nil
+	}
+
+	app, err := resolveApp(ctx, fz, args[0])
+	if err != nil {
+		return err
+	}
+	if launchFlags.exit {
+		if err := fz.ExitApp(ctx); err != nil {
+			return fmt.Errorf("failed to exit the running app: %w", err)
+		}
+	}
+	if err := fz.StartApp(ctx, app, launchFlags.args); err != nil {
+		if errors.Is(err, recfz.ErrAppLocked) {
+			return fmt.Errorf("%w, use --exit to exit it first", err)
+		}
+		return fmt.Errorf("%s: %w", app, err)
+	}
+	return nil
+}
+
+// resolveApp returns the path of an installed app if name is the name of one.
+// Built-in apps and paths are returned unchanged.
+func resolveApp(ctx context.Context, fz *recfz.FlipperZero, name string) (string, error) {
+	if strings.HasPrefix(name, "/") || slices.Contains(recfz.BuiltinApps, name) {
+		return name, nil
+	}
+	installed, err := fz.InstalledApps(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, app := range installed {
+		if recfz.AppName(app) == name {
+			return app, nil
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var listCmd = &coral.Command{
+	Use:          "list",
+	Short:        "List all flippers attached via usb",
+	Args:         coral.NoArgs,
+	SilenceUsage: true,
+	RunE:         list,
+}
+
+func list(_ *coral.Command, _ []string) error {
+	devices, err := recfz.ListDevices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no flipper found")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tSERIAL\tNAME")
+	for _, d := range devices {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Port, d.SerialNumber, d.Name)
+	}
+	return w.Flush()
+}
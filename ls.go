@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var lsFlags struct {
+	long bool
+}
+
+var lsCmd = &coral.Command{
+	Use:          "ls [path]...",
+	Short:        "List files on the flipper (default: /ext)",
+	SilenceUsage: true,
+	RunE:         ls,
+}
+
+func init() {
+	lsCmd.Flags().BoolVarP(&lsFlags.long, "long", "l", false, "show the type and size of the files")
+}
+
+func ls(cmd *coral.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"/ext"}
+	}
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	ctx := cmd.Context()
+
+	var paths []string
+	for _, arg := range args {
+		matches, err := expandRemote(ctx, fz, arg)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	// like ls, files are listed first, followed by the content of the directories
+	var failed bool
+	var dirs []string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, p := range paths {
+		fi, err := fz.Stat(ctx, p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		if fi.IsDir() {
+			dirs = append(dirs, p)
+			continue
+		}
+		printFile(w, fi, p)
+	}
+	for i, p := range dirs {
+		files, err := fz.List(ctx, p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		// name the directories if there is more than one thing to list
+		if len(paths) > 1 {
+			if i > 0 || len(dirs) < len(paths) {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", p)
+		}
+		for _, f := range files {
+			printFile(w, f, f.Name())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed {
+		return fmt.Errorf("could not list all files")
+	}
+	return nil
+}
+
+// printFile prints a single line of the listing.
+func printFile(w *tabwriter.Writer, fi fs.FileInfo, name string) {
+	if fi.IsDir() {
+		name = path.Clean(name) + "/"
+	}
+	if !lsFlags.long {
+		fmt.Fprintln(w, name)
+		return
+	}
+	if fi.IsDir() {
+		fmt.Fprintf(w, "d\t-\t%s\n", name)
+		return
+	}
+	fmt.Fprintf(w, "-\t%d\t%s\n", fi.Size(), name)
+}
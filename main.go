@@ -5,8 +5,11 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jon4hz/fztea/fakefz"
 	"github.com/jon4hz/fztea/flipperui"
 	"github.com/jon4hz/fztea/internal/version"
 	"github.com/jon4hz/fztea/recfz"
@@ -20,6 +23,19 @@ var rootFlags struct {
 	screenshotResolution string
 	fgColor              string
 	bgColor              string
+	theme                string
+	demo                 bool
+	maxReconnects        int
+	reconnectTimeout     time.Duration
+	maxBackoff           time.Duration
+	handshakeTimeout     time.Duration
+	wait                 bool
+	serialNumber         string
+	name                 string
+	statusBar            bool
+	skin                 bool
+	renderer             string
+	scale                int
 }
 
 var rootCmd = &coral.Command{
@@ -30,12 +46,25 @@ var rootCmd = &coral.Command{
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&rootFlags.port, "port", "p", "", "serial port to connect to (default: auto-detected)")
+	rootCmd.PersistentFlags().StringVarP(&rootFlags.port, "port", "p", "", "serial port or tcp://host:port to connect to (default: auto-detected)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.serialNumber, "serial", "", "usb serial number of the flipper to connect to (see fztea list)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.name, "name", "", "name of the flipper to connect to (see fztea list)")
 	rootCmd.PersistentFlags().StringVar(&rootFlags.screenshotResolution, "screenshot-resolution", "1024x512", "screenshot resolution")
-	rootCmd.PersistentFlags().StringVar(&rootFlags.fgColor, "fg-color", "#000000", "foreground color")
-	rootCmd.PersistentFlags().StringVar(&rootFlags.bgColor, "bg-color", "#FF8C00", "background color")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.theme, "theme", flipperui.DefaultTheme.Name, "color theme, built-in or the name of a theme file (see README)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.fgColor, "fg-color", "", "foreground color of the screen (default: color of the theme)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.bgColor, "bg-color", "", "background color of the screen (default: color of the theme)")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.demo, "demo", false, "run against a simulated flipper instead of a real device")
+	rootCmd.PersistentFlags().IntVar(&rootFlags.maxReconnects, "max-reconnects", 0, "give up after this many reconnect attempts (0: unlimited)")
+	rootCmd.PersistentFlags().DurationVar(&rootFlags.reconnectTimeout, "reconnect-timeout", 0, "give up reconnecting after this duration (0: unlimited)")
+	rootCmd.PersistentFlags().DurationVar(&rootFlags.maxBackoff, "max-backoff", 30*time.Second, "maximum delay between two reconnect attempts")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.wait, "wait", false, "wait for the flipper to be plugged in instead of failing")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.statusBar, "status-bar", false, "show a status bar below the screen")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.skin, "skin", false, "draw the flipper around the screen, with buttons that can be clicked")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.renderer, "renderer", flipperui.RendererAuto, "renderer drawing the screen: "+strings.Join(flipperui.Renderers, ", "))
+	rootCmd.PersistentFlags().IntVar(&rootFlags.scale, "scale", 0, "scale the screen is drawn at (0: fit to the terminal)")
+	rootCmd.PersistentFlags().DurationVar(&rootFlags.handshakeTimeout, "handshake-timeout", 10*time.Second, "timeout to establish the rpc session")
 
-	rootCmd.AddCommand(serverCmd, versionCmd, manCmd)
+	rootCmd.AddCommand(serverCmd, dashboardCmd, broadcastCmd, listCmd, lsCmd, pullCmd, pushCmd, rmCmd, mkdirCmd, backupCmd, restoreCmd, syncCmd, infoCmd, launchCmd, versionCmd, manCmd)
 }
 
 func root(cmd *coral.Command, _ []string) {
@@ -44,13 +73,22 @@ func root(cmd *coral.Command, _ []string) {
 	if err != nil {
 		log.Fatalf("failed to parse screenshot resolution: %s", err)
 	}
+	renderers, err := flipperui.NewRenderers(rootFlags.renderer, os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	themes, err := themeOpts()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	screenUpdates := make(chan flipperui.ScreenMsg)
+	screens := flipperui.NewBroadcaster()
 	fz, err := recfz.NewFlipperZero(
-		recfz.WithContext(cmd.Context()),
-		recfz.WithPort(rootFlags.port),
-		recfz.WithStreamScreenCallback(flipperui.UpdateScreen(screenUpdates)),
-		recfz.WithLogger(log.New(io.Discard, "", 0)),
+		append(connOpts(),
+			recfz.WithContext(cmd.Context()),
+			recfz.WithStreamScreenCallback(screens.Callback()),
+			recfz.WithLogger(log.New(io.Discard, "", 0)),
+		)...,
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -60,12 +98,28 @@ func root(cmd *coral.Command, _ []string) {
 	if err := fz.Connect(); err != nil {
 		log.Fatal(err)
 	}
+	sub := screens.Subscribe()
+	defer sub.Close()
+	states := fz.Subscribe()
+	defer states.Close()
+
+	opts := append(themes,
+		flipperui.WithScreenshotResolution(screenshotResolution.width, screenshotResolution.height),
+		flipperui.WithStateUpdates(states.Events()),
+		flipperui.WithRenderers(renderers...),
+		flipperui.WithScale(rootFlags.scale),
+	)
+	if rootFlags.statusBar {
+		opts = append(opts, flipperui.WithStatusBar())
+	}
+	if rootFlags.skin {
+		opts = append(opts, flipperui.WithSkin())
+	}
 	m := model{
-		flipper: flipperui.New(fz, screenUpdates,
-			flipperui.WithScreenshotResolution(screenshotResolution.width, screenshotResolution.height),
-			flipperui.WithFgColor(rootFlags.fgColor),
-			flipperui.WithBgColor(rootFlags.bgColor),
-		),
+		flipper:  flipperui.New(fz, sub.Updates(), opts...),
+		browser:  flipperui.NewBrowser(fz),
+		info:     flipperui.NewInfoPanel(fz),
+		launcher: flipperui.NewLauncher(fz),
 	}
 	if _, err := tea.NewProgram(m, tea.WithMouseCellMotion()).Run(); err != nil {
 		log.Fatalln(err)
@@ -107,6 +161,80 @@ var versionCmd = &coral.Command{
 	},
 }
 
+// connOpts returns the options telling recfz how to reach the flipper.
+// In demo mode, a simulated flipper is used.
+func connOpts() []recfz.Opts {
+	opts := []recfz.Opts{
+		recfz.WithPort(rootFlags.port),
+		recfz.WithSerialNumber(rootFlags.serialNumber),
+		recfz.WithDeviceName(rootFlags.name),
+		recfz.WithReconnectPolicy(reconnectPolicy()),
+	}
+	if rootFlags.demo {
+		opts = append(opts, recfz.WithTransport(fakefz.NewDevice().Transport()))
+	}
+	if rootFlags.wait {
+		opts = append(opts, recfz.WithWaitForDevice(time.Second))
+	}
+	return opts
+}
+
+// connect connects to the flipper for the commands without a TUI.
+// With --wait, it returns right away and the flipper is connected in the background.
+func connect(cmd *coral.Command, opts ...recfz.Opts) (*recfz.FlipperZero, error) {
+	opts = append(connOpts(),
+		append([]recfz.Opts{
+			recfz.WithContext(cmd.Context()),
+			recfz.WithLogger(log.New(io.Discard, "", 0)),
+		}, opts...)...,
+	)
+	fz, err := recfz.NewFlipperZero(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := fz.Connect(); err != nil {
+		fz.Close()
+		return nil, err
+	}
+	return fz, nil
+}
+
+// themeOpts returns the options setting the theme and the colors of the screen from the root flags.
+// The user's theme files are loaded as well, so they can be cycled through in the TUI.
+func themeOpts() ([]flipperui.FlipperOpts, error) {
+	themes := flipperui.Themes
+	if dir, err := flipperui.ThemeDir(); err == nil {
+		if themes, err = flipperui.LoadThemes(dir); err != nil {
+			return nil, err
+		}
+	}
+	theme, err := flipperui.FindTheme(themes, rootFlags.theme)
+	if err != nil {
+		return nil, err
+	}
+	opts := []flipperui.FlipperOpts{flipperui.WithTheme(theme), flipperui.WithThemes(themes...)}
+	if rootFlags.fgColor != "" {
+		opts = append(opts, flipperui.WithFgColor(rootFlags.fgColor))
+	}
+	if rootFlags.bgColor != "" {
+		opts = append(opts, flipperui.WithBgColor(rootFlags.bgColor))
+	}
+	return opts, nil
+}
+
+// reconnectPolicy returns the reconnect policy derived from the root flags.
+func reconnectPolicy() recfz.ReconnectPolicy {
+	return recfz.ReconnectPolicy{
+		InitialBackoff:   time.Second,
+		MaxBackoff:       rootFlags.maxBackoff,
+		Multiplier:       2,
+		Jitter:           0.2,
+		MaxAttempts:      rootFlags.maxReconnects,
+		MaxElapsed:       rootFlags.reconnectTimeout,
+		HandshakeTimeout: rootFlags.handshakeTimeout,
+	}
+}
+
 func parseScreenshotResolution() (struct {
 	width  int
 	height int
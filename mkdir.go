@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var mkdirFlags struct {
+	parents bool
+}
+
+var mkdirCmd = &coral.Command{
+	Use:          "mkdir path...",
+	Short:        "Create directories on the flipper",
+	Args:         coral.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         mkdir,
+}
+
+func init() {
+	// -p is taken by --port
+	mkdirCmd.Flags().BoolVar(&mkdirFlags.parents, "parents", false, "create missing parents, don't fail if the directory exists")
+}
+
+func mkdir(cmd *coral.Command, args []string) error {
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+
+	var failed bool
+	for _, p := range args {
+		if mkdirFlags.parents {
+			err = mkdirAllRemote(cmd.Context(), fz, p)
+		} else {
+			err = fz.Mkdir(cmd.Context(), p)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("could not create all directories")
+	}
+	return nil
+}
@@ -1,18 +1,68 @@
 package main
 
 import (
+	"fmt"
+	"math"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jon4hz/fztea/flipperui"
 )
 
+// driverStyle returns the style of the control indicator if the session controls the flipper.
+func driverStyle(theme flipperui.Theme) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent)).Bold(true)
+}
+
+// mutedStyle returns the style of hints, e.g. the control indicator if the session only watches.
+func mutedStyle(theme flipperui.Theme) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+}
+
 type model struct {
 	flipper       tea.Model
 	width, height int
+
+	// ctrl and viewer are only set if the session is shared with others (e.g. ssh server).
+	ctrl    *controller
+	viewer  *viewer
+	control controlMsg
+
+	// browser is the file browser shown next to the screen. It's only set for local sessions.
+	browser  tea.Model
+	browsing bool
+
+	// info is the info panel shown next to the screen. It's queried the first time it's shown.
+	info        tea.Model
+	showInfo    bool
+	infoStarted bool
+
+	// launcher is the popup starting apps. It's only set for local sessions and lists the apps the first time it's shown.
+	launcher        tea.Model
+	launching       bool
+	launcherStarted bool
+
+	// theme is set by the flipper, which cycles it
+	theme flipperui.Theme
 }
 
+const (
+	// paneGap is the space between the screen and the panes next to it.
+	paneGap = 2
+	// browserWidth is the width kept free for the file browser when the screen is fit into the terminal.
+	browserWidth = 60
+)
+
 // Init is the bubbletea init function.
 func (m model) Init() tea.Cmd {
-	return m.flipper.Init()
+	cmds := []tea.Cmd{m.flipper.Init()}
+	if m.viewer != nil {
+		cmds = append(cmds, listenControl(m.viewer))
+	}
+	if m.browser != nil {
+		cmds = append(cmds, m.browser.Init())
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update is the bubbletea update function and handles all tea.Msgs.
@@ -22,19 +72,216 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
+		case "ctrl+t":
+			if m.ctrl != nil {
+				m.ctrl.Request(m.viewer)
+				return m, nil
+			}
+		case "ctrl+g":
+			if m.ctrl != nil {
+				m.ctrl.HandOver(m.viewer)
+				return m, nil
+			}
+		case "ctrl+e":
+			if m.browser != nil {
+				m.browsing = !m.browsing
+				m.showInfo, m.launching = false, false
+				return m, m.resize()
+			}
+		case "ctrl+p":
+			if m.info != nil {
+				m.showInfo = !m.showInfo
+				m.browsing, m.launching = false, false
+				if !m.infoStarted {
+					m.infoStarted = true
+					return m, tea.Batch(m.info.Init(), m.resize())
+				}
+				return m, m.resize()
+			}
+		case "ctrl+l":
+			if m.launcher != nil {
+				m.launching = !m.launching
+				m.browsing, m.showInfo = false, false
+				if !m.launcherStarted {
+					m.launcherStarted = true
+					return m, tea.Batch(m.launcher.Init(), m.resize())
+				}
+				return m, m.resize()
+			}
+		case "ctrl+o":
+			// the flipper cycles the theme, even while a pane has the focus
+			var cmd tea.Cmd
+			m.flipper, cmd = m.flipper.Update(msg)
+			return m, cmd
+		case "esc":
+			if m.launching {
+				m.launching = false
+				return m, m.resize()
+			}
 		}
+		// keys only reach the focused pane, the panes in the background must not act on them
+		var cmd tea.Cmd
+		switch {
+		case m.launching:
+			m.launcher, cmd = m.launcher.Update(msg)
+		case m.browsing:
+			m.browser, cmd = m.browser.Update(msg)
+		default:
+			m.flipper, cmd = m.flipper.Update(msg)
+		}
+		return m, cmd
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		return m, m.resize()
+
+	case tea.MouseMsg:
+		if m.browsing || m.launching {
+			return m, nil
+		}
+		// the flipper expects the position relative to its view, e.g. to click the buttons of the skin
+		x, y := m.flipperOrigin()
+		msg.X, msg.Y = msg.X-x, msg.Y-y
+		var cmd tea.Cmd
+		m.flipper, cmd = m.flipper.Update(msg)
+		return m, cmd
+
+	case flipperui.ThemeMsg:
+		// the theme is passed on to the flipper and the panes below
+		m.theme = msg.Theme
+
+	case controlMsg:
+		m.control = msg
+		return m, listenControl(m.viewer)
 	}
 
 	var cmd tea.Cmd
 	m.flipper, cmd = m.flipper.Update(msg)
+	if m.browser != nil {
+		var browserCmd tea.Cmd
+		m.browser, browserCmd = m.browser.Update(msg)
+		cmd = tea.Batch(cmd, browserCmd)
+	}
+	if m.info != nil {
+		var infoCmd tea.Cmd
+		m.info, infoCmd = m.info.Update(msg)
+		cmd = tea.Batch(cmd, infoCmd)
+	}
+	if m.launcher != nil {
+		var launcherCmd tea.Cmd
+		m.launcher, launcherCmd = m.launcher.Update(msg)
+		cmd = tea.Batch(cmd, launcherCmd)
+	}
 	return m, cmd
 }
 
+// resize passes the size left for the screen to the flipper and the size next to it to the panes.
+func (m *model) resize() tea.Cmd {
+	if m.width == 0 && m.height == 0 {
+		// the size isn't known yet
+		return nil
+	}
+	cmds := make([]tea.Cmd, 3)
+	m.flipper, cmds[0] = m.flipper.Update(m.screenSize())
+	if m.browser != nil {
+		m.browser, cmds[1] = m.browser.Update(m.browserSize())
+	}
+	if m.launcher != nil {
+		m.launcher, cmds[2] = m.launcher.Update(m.browserSize())
+	}
+	return tea.Batch(cmds...)
+}
+
+// screenSize returns the size left for the screen by the open pane and the control indicator.
+func (m model) screenSize() tea.WindowSizeMsg {
+	width, height := m.width, m.height
+	switch {
+	case m.browsing:
+		width -= browserWidth + paneGap
+	case m.pane() != nil:
+		width -= lipgloss.Width(m.pane().View()) + paneGap
+	}
+	if m.ctrl != nil {
+		height--
+	}
+	return tea.WindowSizeMsg{Width: max(0, width), Height: max(0, height)}
+}
+
+// pane returns the pane shown next to the screen, if any.
+func (m model) pane() tea.Model {
+	switch {
+	case m.browsing:
+		return m.browser
+	case m.showInfo:
+		return m.info
+	case m.launching:
+		return m.launcher
+	}
+	return nil
+}
+
+// browserSize returns the size of the file browser or the launcher next to the screen.
+func (m model) browserSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{
+		Width:  max(0, m.width-lipgloss.Width(m.flipper.View())-paneGap),
+		Height: lipgloss.Height(m.flipper.View()),
+	}
+}
+
 // View is the bubbletea view function.
 func (m model) View() string {
-	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.flipper.View())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.content())
+}
+
+// flipperOrigin returns the position of the top left corner of the flipper view in the terminal.
+// It mirrors the layout of View and content.
+func (m model) flipperOrigin() (x, y int) {
+	content := m.content()
+	x = centerOffset(m.width - lipgloss.Width(content))
+	y = centerOffset(m.height - lipgloss.Height(content))
+	if m.ctrl != nil {
+		// JoinVertical centers the flipper above the control indicator
+		if gap := lipgloss.Width(m.controlView()) - lipgloss.Width(m.flipper.View()); gap > 0 {
+			x += int(math.Round(float64(gap) / 2))
+		}
+	}
+	return x, y
+}
+
+// centerOffset returns the space lipgloss.Place puts before the content to center it in a gap.
+func centerOffset(gap int) int {
+	if gap <= 0 {
+		return 0
+	}
+	return gap - int(math.Round(float64(gap)/2))
+}
+
+// content renders the screen, the control indicator and the open pane.
+func (m model) content() string {
+	screen := m.flipper.View()
+	if m.ctrl != nil {
+		screen = lipgloss.JoinVertical(lipgloss.Center, screen, m.controlView())
+	}
+	if pane := m.pane(); pane != nil {
+		screen = lipgloss.JoinHorizontal(lipgloss.Top, screen, lipgloss.NewStyle().MarginLeft(paneGap).Render(pane.View()))
+	}
+	return screen
+}
+
+// controlView renders the indicator showing who is controlling the flipper.
+func (m model) controlView() string {
+	if m.control.isDriver {
+		s := fmt.Sprintf("● you are driving (%d watching)", m.control.viewers-1)
+		if m.control.waiting > 0 {
+			s += fmt.Sprintf(" - %d waiting, ctrl+g to hand over", m.control.waiting)
+		} else if m.control.viewers > 1 {
+			s += " - ctrl+g to hand over"
+		}
+		return driverStyle(m.theme).Render(s)
+	}
+	if m.control.requested {
+		return mutedStyle(m.theme).Render(fmt.Sprintf("○ %s is driving - control requested", m.control.driver))
+	}
+	return mutedStyle(m.theme).Render(fmt.Sprintf("○ %s is driving - ctrl+t to request control", m.control.driver))
 }
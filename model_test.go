@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"slices"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/flipperui"
+	"github.com/jon4hz/fztea/internal/race"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+// recorder is a tea.Model which records the keys and clicks it receives.
+type recorder struct {
+	name string
+	log  *[]string
+}
+
+func (r recorder) Init() tea.Cmd { return nil }
+
+func (r recorder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		*r.log = append(*r.log, r.name+" "+msg.String())
+	case tea.MouseMsg:
+		*r.log = append(*r.log, r.name+" click")
+	}
+	return r, nil
+}
+
+func (r recorder) View() string { return r.name }
+
+// newRecordingModel returns a model whose flipper and panes record their input in log.
+func newRecordingModel(log *[]string) tea.Model {
+	return model{
+		flipper:  recorder{name: "flipper", log: log},
+		browser:  recorder{name: "browser", log: log},
+		info:     recorder{name: "info", log: log},
+		launcher: recorder{name: "launcher", log: log},
+		width:    80,
+		height:   24,
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "ctrl+e":
+		return tea.KeyMsg{Type: tea.KeyCtrlE}
+	case "ctrl+p":
+		return tea.KeyMsg{Type: tea.KeyCtrlP}
+	case "ctrl+l":
+		return tea.KeyMsg{Type: tea.KeyCtrlL}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// send passes the messages to the model and returns what the flipper and the panes received.
+func send(m tea.Model, msgs ...tea.Msg) []string {
+	log := m.(model).flipper.(recorder).log
+	*log = nil
+	for _, msg := range msgs {
+		m, _ = m.Update(msg)
+	}
+	return *log
+}
+
+func TestKeysReachOnlyTheScreen(t *testing.T) {
+	var log []string
+	m := newRecordingModel(&log)
+
+	// keys of the hidden browser, e.g. d downloads the selected file
+	got := send(m, key("d"), key("enter"), key("l"))
+	want := []string{"flipper d", "flipper enter", "flipper l"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestKeysReachOnlyTheBrowser(t *testing.T) {
+	var log []string
+	m := newRecordingModel(&log)
+
+	got := send(m, key("ctrl+e"), key("d"), key("enter"), tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	want := []string{"browser d", "browser enter"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestKeysWithInfoPanel(t *testing.T) {
+	var log []string
+	m := newRecordingModel(&log)
+
+	// the info panel has no keys, the screen keeps them
+	got := send(m, key("ctrl+p"), key("enter"))
+	want := []string{"flipper enter"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestClicksReachOnlyTheScreen(t *testing.T) {
+	var log []string
+	m := newRecordingModel(&log)
+
+	got := send(m, tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	want := []string{"flipper click"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestKeysReachOnlyTheLauncher(t *testing.T) {
+	var log []string
+	m := newRecordingModel(&log)
+
+	// enter on the screen must not start the app selected in the closed launcher
+	got := send(m, key("enter"), key("ctrl+l"), key("enter"), tea.KeyMsg{Type: tea.KeyEsc}, key("enter"))
+	want := []string{"flipper enter", "launcher enter", "flipper enter"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestClosedLauncherStartsNoApp(t *testing.T) {
+	race.SkipGoFlipper(t)
+	d := fakefz.NewDevice()
+	defer d.Close()
+	fz, err := recfz.NewFlipperZero(recfz.WithTransport(d.Transport()), recfz.WithoutScreenStream(), recfz.WithLogger(log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fz.Close()
+	if err := fz.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var log []string
+	var m tea.Model = model{
+		flipper:  recorder{name: "flipper", log: &log},
+		launcher: flipperui.NewLauncher(fz),
+	}
+	// the launcher selects Sub-GHz, enter on the screen must not start it
+	m, cmd := m.Update(key("enter"))
+	if cmd != nil {
+		t.Fatal("enter on the screen started an app")
+	}
+	if locked, err := fz.AppLocked(ctx); err != nil || locked {
+		t.Fatalf("expected no app to run: %v", err)
+	}
+
+	m, _ = m.Update(key("ctrl+l"))
+	_, cmd = m.Update(key("enter"))
+	if cmd == nil {
+		t.Fatal("enter in the launcher didn't start the app")
+	}
+	cmd()
+	if locked, err := fz.AppLocked(ctx); err != nil || !locked {
+		t.Fatalf("expected the app to run: %v", err)
+	}
+}
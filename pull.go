@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var pullCmd = &coral.Command{
+	Use:   "pull remote... local",
+	Short: "Copy files and directories from the flipper",
+	Example: "  fztea pull /ext/subghz ./backup\n" +
+		"  fztea pull '/ext/nfc/*.nfc' .",
+	Args:         coral.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE:         pull,
+}
+
+func init() {
+	addTransferFlags(pullCmd)
+}
+
+func pull(cmd *coral.Command, args []string) error {
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	t, err := newTransfer(cmd.Context(), fz)
+	if err != nil {
+		return err
+	}
+
+	dst := args[len(args)-1]
+	var srcs []string
+	for _, arg := range args[:len(args)-1] {
+		matches, err := expandRemote(t.ctx, fz, arg)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, matches...)
+	}
+
+	// like cp, copy into the destination if it's a directory
+	intoDir := len(srcs) > 1 || strings.HasSuffix(dst, string(filepath.Separator)) || strings.HasSuffix(dst, "/")
+	if fi, err := os.Stat(dst); err == nil && fi.IsDir() {
+		intoDir = true
+	}
+	if intoDir {
+		if err := os.MkdirAll(dst, 0o755); err != nil {
+			return err
+		}
+	}
+
+	for _, src := range srcs {
+		target := dst
+		if intoDir {
+			target = filepath.Join(dst, path.Base(src))
+		}
+		t.pull(src, target)
+	}
+	return t.err()
+}
+
+// pull copies a file or directory from the flipper.
+func (t *transfer) pull(src, dst string) {
+	fi, err := t.fz.Stat(t.ctx, src)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	if !fi.IsDir() {
+		t.pullFile(src, dst)
+		return
+	}
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		t.fail(err)
+		return
+	}
+	files, err := t.fz.List(t.ctx, src)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	for _, f := range files {
+		t.pull(path.Join(src, f.Name()), filepath.Join(dst, f.Name()))
+	}
+}
+
+// pullFile copies a single file from the flipper.
+func (t *transfer) pullFile(src, dst string) {
+	if _, err := os.Stat(dst); err == nil && t.skip(dst) {
+		return
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.fail(err)
+		return
+	}
+
+	n, err := t.download(src, dst)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.copied(src, dst, n)
+}
+
+// download reads a file from the flipper and writes it to a local file.
+// The file is written once it was read completely, so there are no partial files.
+func (t *transfer) download(src, dst string) (int64, error) {
+	bar := newProgressBar(src)
+	var buf bytes.Buffer
+	err := t.retry(func() error {
+		buf.Reset()
+		_, err := t.fz.ReadFile(t.ctx, src, &buf, bar.update)
+		return err
+	})
+	bar.clear()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(dst, buf.Bytes(), 0o644); err != nil { //nolint:gosec
+		return 0, err
+	}
+	return int64(buf.Len()), nil
+}
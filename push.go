@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var pushCmd = &coral.Command{
+	Use:   "push local... remote",
+	Short: "Copy files and directories to the flipper",
+	Example: "  fztea push ./badusb/*.txt /ext/badusb\n" +
+		"  fztea push ./backup/subghz /ext",
+	Args:         coral.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE:         push,
+}
+
+func init() {
+	addTransferFlags(pushCmd)
+}
+
+func push(cmd *coral.Command, args []string) error {
+	var srcs []string
+	for _, arg := range args[:len(args)-1] {
+		matches, err := expandLocal(arg)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, matches...)
+	}
+
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	t, err := newTransfer(cmd.Context(), fz)
+	if err != nil {
+		return err
+	}
+
+	// like cp, copy into the destination if it's a directory
+	dst := args[len(args)-1]
+	intoDir := len(srcs) > 1 || strings.HasSuffix(dst, "/")
+	dst = path.Clean(dst)
+	if fi, err := fz.Stat(t.ctx, dst); err == nil && fi.IsDir() {
+		intoDir = true
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if intoDir {
+		if err := mkdirAllRemote(t.ctx, fz, dst); err != nil {
+			return err
+		}
+	}
+
+	for _, src := range srcs {
+		target := dst
+		if intoDir {
+			target = path.Join(dst, filepath.Base(src))
+		}
+		t.push(src, target)
+	}
+	return t.err()
+}
+
+// push copies a file or directory to the flipper.
+func (t *transfer) push(src, dst string) {
+	fi, err := os.Stat(src)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	if !fi.IsDir() {
+		t.pushFile(src, dst)
+		return
+	}
+
+	if err := mkdirAllRemote(t.ctx, t.fz, dst); err != nil {
+		t.fail(err)
+		return
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	for _, e := range entries {
+		t.push(filepath.Join(src, e.Name()), path.Join(dst, e.Name()))
+	}
+}
+
+// pushFile copies a single file to the flipper.
+func (t *transfer) pushFile(src, dst string) {
+	if _, err := t.fz.Stat(t.ctx, dst); err == nil && t.skip(dst) {
+		return
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.fail(err)
+		return
+	}
+
+	n, err := t.upload(src, dst)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.copied(src, dst, n)
+}
+
+// upload writes a local file to the flipper, overwriting an existing file.
+func (t *transfer) upload(src, dst string) (int64, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return 0, err
+	}
+	bar := newProgressBar(src)
+	err = t.retry(func() error {
+		_, err := t.fz.WriteFile(t.ctx, dst, bytes.NewReader(data), bar.update)
+		return err
+	})
+	bar.clear()
+	return int64(len(data)), err
+}
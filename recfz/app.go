@@ -0,0 +1,117 @@
+package recfz
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+)
+
+const (
+	// AppsDir is the directory on the sd card containing the installed apps.
+	AppsDir = "/ext/apps"
+	// appExt is the extension of installed apps.
+	appExt = ".fap"
+
+	// maxExitPresses is the maximum number of back presses sent to exit the running app.
+	maxExitPresses = 10
+	// exitPressInterval is the time the running app gets to handle a back press.
+	exitPressInterval = 300 * time.Millisecond
+)
+
+var (
+	// ErrAppLocked is returned if an app can't be started because another app is running.
+	ErrAppLocked = errors.New("app already running")
+	// ErrAppCantStart is returned if the flipper zero failed to start an app, e.g. because it doesn't exist.
+	ErrAppCantStart = errors.New("can't start app")
+)
+
+// BuiltinApps are the names of the apps built into the firmware, which can be started by name.
+var BuiltinApps = []string{
+	"Sub-GHz", "125 kHz RFID", "NFC", "Infrared", "GPIO", "iButton", "Bad USB", "U2F", "Archive", "Settings",
+}
+
+// StartApp starts an app by its name or, for installed apps, by the path of its .fap file.
+// args are passed to the app, e.g. the path of a file to open.
+// It returns ErrAppLocked if another app is running.
+func (f *FlipperZero) StartApp(ctx context.Context, name, args string) error {
+	return f.rpc(ctx, func(fl *flipper.Flipper) error {
+		return fl.App.Start(name, args)
+	})
+}
+
+// AppLocked returns true if an app is running, which prevents starting another one.
+func (f *FlipperZero) AppLocked(ctx context.Context) (bool, error) {
+	var locked bool
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		var err error
+		locked, err = fl.App.IsLocked()
+		return err
+	})
+	return locked, err
+}
+
+// ExitApp exits the running app by pressing back until the flipper zero is unlocked.
+// The rpc client can't close apps directly, so apps asking for confirmation may not exit.
+// It returns ErrAppLocked if the app is still running after maxExitPresses.
+func (f *FlipperZero) ExitApp(ctx context.Context) error {
+	for i := 0; ; i++ {
+		locked, err := f.AppLocked(ctx)
+		if err != nil || !locked {
+			return err
+		}
+		if i == maxExitPresses {
+			return ErrAppLocked
+		}
+		if err := f.SendShortPress(flipper.InputKeyBack); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(exitPressInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// InstalledApps returns the paths of the .fap files in AppsDir and its subdirectories, sorted by path.
+// If there is no sd card or no apps directory, it returns no apps.
+func (f *FlipperZero) InstalledApps(ctx context.Context) ([]string, error) {
+	var apps []string
+	dirs := []string{AppsDir}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		entries, err := f.List(ctx, dir)
+		if err != nil {
+			if dir == AppsDir && errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			p := path.Join(dir, e.Name())
+			switch {
+			case e.IsDir():
+				dirs = append(dirs, p)
+			case strings.EqualFold(path.Ext(p), appExt):
+				apps = append(apps, p)
+			}
+		}
+	}
+	sort.Strings(apps)
+	return apps, nil
+}
+
+// AppName returns the name of an app shown to the user, e.g. snake_game for /ext/apps/Games/snake_game.fap.
+func AppName(app string) string {
+	if !strings.EqualFold(path.Ext(app), appExt) {
+		return app
+	}
+	return strings.TrimSuffix(path.Base(app), path.Ext(app))
+}
@@ -9,100 +9,150 @@ import (
 	"time"
 
 	"github.com/flipperdevices/go-flipper"
-	"go.bug.st/serial"
-	"go.bug.st/serial/enumerator"
 )
 
 // Connect connects to the flipper zero device.
-// It will indefinitely try to reconnect if the connection is lost.
+// If the connection is lost, it will try to reconnect according to the reconnect policy.
+// If the flipper zero waits for the device, Connect returns immediately and connects in the background.
 func (f *FlipperZero) Connect() error {
-	if err := f.reconnect(); err != nil {
+	if f.waitInterval > 0 {
+		go f.waitLoop()
+		return nil
+	}
+	if err := f.reconnect(0); err != nil {
+		f.emit(Event{State: StateDisconnected, Err: err})
 		return err
 	}
 	go f.reconnLoop()
 	return nil
 }
 
+// waitLoop waits for the device to show up and connects to it.
+// It polls until the connection succeeds or the flipper zero is closed.
+func (f *FlipperZero) waitLoop() {
+	ticker := time.NewTicker(f.waitInterval)
+	defer ticker.Stop()
+	f.emit(Event{State: StateWaiting})
+	for {
+		if f.available() {
+			err := f.reconnect(0)
+			if err == nil {
+				f.reconnLoop()
+				return
+			}
+			f.logger.Printf("could not connect: %v", err)
+			f.emit(Event{State: StateWaiting, Err: err})
+		}
+		select {
+		case <-ticker.C:
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
+
+// available returns true if the transport can't tell or reports the device as available.
+func (f *FlipperZero) available() bool {
+	d, ok := f.transport.(Detector)
+	if !ok {
+		return true
+	}
+	return d.Available() == nil
+}
+
 // reconnect starts a new connection to the flipper zero device.
-func (f *FlipperZero) reconnect() error {
-	conn, err := f.newConn()
+// The attempt is reported in the state events, 0 means initial connect.
+func (f *FlipperZero) reconnect(attempt int) error {
+	f.emit(Event{State: StateConnecting, Attempt: attempt})
+	conn, err := f.newConn(attempt)
 	if err != nil {
-		return fmt.Errorf("could not open serial conn: %w", err)
+		return fmt.Errorf("could not open conn: %w", err)
 	}
-	fz, err := flipper.ConnectWithTimeout(conn, 10*time.Second)
+	t := newTap(conn)
+	fz, err := flipper.ConnectWithTimeout(struct {
+		io.Reader
+		io.Writer
+	}{t, conn}, f.policy.handshakeTimeout())
 	if err != nil {
+		conn.Close()
 		return fmt.Errorf("could not connect to flipper: %w", err)
 	}
 	f.logger.Println("successfully connected to flipper")
 
+	f.mu.Lock()
+	f.tap = t
+	f.mu.Unlock()
 	f.SetFlipper(fz)
 	f.SetConn(conn)
 
-	return f.startScreenStream()
+	if !f.noScreenStream {
+		if err := f.startScreenStream(); err != nil {
+			return err
+		}
+	}
+
+	info, err := fz.System.DeviceInfo()
+	if err != nil {
+		f.logger.Printf("could not get device info: %s", err)
+	}
+	f.emit(Event{State: StateConnected, Info: info})
+	return nil
 }
 
-// newConn opens a new serial connection to the flipper zero device.
-// If the port is not static, it will try to autodetect the flipper zero device.
+// newConn opens a new connection to the flipper zero device using the configured transport.
 // If the connection is already open, it will be closed and a new one will be opened.
-// If the connection is openend successfully, it will start an rpc session over serial.
-func (f *FlipperZero) newConn() (serial.Port, error) {
-	port := f.port
-	if !f.staticPort {
-		var err error
-		port, err = f.autodetectFlipper()
-		if err != nil {
-			return nil, err
-		}
-	}
+// If the connection is openend successfully, it will start an rpc session over it.
+func (f *FlipperZero) newConn(attempt int) (Conn, error) {
 	if conn := f.getConn(); conn != nil {
 		conn.Close()
 	}
-	ser, err := serial.Open(port, &serial.Mode{})
+	conn, err := f.transport.Dial(f.ctx)
 	if err != nil {
 		return nil, err
 	}
-	br := bufio.NewReader(ser)
-	_, err = readUntil(br, []byte("\r\n\r\n>: "))
-	if err != nil {
-		return nil, err
+	f.emit(Event{State: StateHandshaking, Attempt: attempt})
+	timeout := f.policy.handshakeTimeout()
+	// the handshake blocks on reads, so the conn is closed if it takes too long
+	timer := time.AfterFunc(timeout, func() { conn.Close() })
+	err = startRPCSession(conn)
+	if !timer.Stop() {
+		return nil, fmt.Errorf("handshake timed out after %s", timeout)
 	}
-	_, err = ser.Write([]byte(startRPCSessionCommand))
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	token, err := br.ReadString('\r')
+	go f.checkConnLoop(conn)
+	f.logger.Printf("successfully opened connection to flipper on %s", f.transport)
+	return conn, nil
+}
+
+// startRPCSession waits for the cli prompt and switches the cli to an rpc session.
+func startRPCSession(conn Conn) error {
+	br := bufio.NewReader(conn)
+	_, err := readUntil(br, []byte("\r\n\r\n>: "))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if token != startRPCSessionCommand {
-		return nil, errors.New(strings.TrimSpace(token))
+	_, err = conn.Write([]byte(startRPCSessionCommand))
+	if err != nil {
+		return err
 	}
 
-	go f.checkConnLoop(ser)
-	f.logger.Println("successfully opened serial connection to flipper")
-	return ser, nil
-}
-
-// autodetectFlipper tries to automatically detect the flipper zero device.
-func (f *FlipperZero) autodetectFlipper() (string, error) {
-	ports, err := enumerator.GetDetailedPortsList()
+	token, err := br.ReadString('\r')
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	for _, p := range ports {
-		if p.PID == flipperPid && p.VID == flipperVid {
-			f.logger.Printf("found flipper on %s", p.Name)
-			return p.Name, nil
-		}
+	if token != startRPCSessionCommand {
+		return errors.New(strings.TrimSpace(token))
 	}
-	return "", errors.New("no flipper found")
+	return nil
 }
 
-// checkConnLoop checks if the connection is still alive by sending an empty message every 2 seconds.
+// checkConnLoop checks if the connection is still alive every 2 seconds.
 // If the connection is lost, it will trigger a reconnect.
-func (f *FlipperZero) checkConnLoop(r io.Writer) {
+func (f *FlipperZero) checkConnLoop(conn Conn) {
 	ticker := time.NewTicker(time.Second * 2)
 	defer ticker.Stop()
 	for {
@@ -113,12 +163,16 @@ func (f *FlipperZero) checkConnLoop(r io.Writer) {
 			if !f.Connected() {
 				continue
 			}
-			_, err := r.Write(nil)
-			if err != nil {
+			// a newer connection took over
+			if f.getConn() != conn {
+				return
+			}
+			if err := conn.Alive(); err != nil {
 				if f.getClosing() {
 					return
 				}
 				f.logger.Printf("could not read from flipper: %s", err)
+				f.emit(Event{State: StateLost, Err: err})
 				f.reconnCh <- struct{}{}
 				return
 			}
@@ -127,7 +181,8 @@ func (f *FlipperZero) checkConnLoop(r io.Writer) {
 }
 
 // reconnLoop tries to reconnect to the flipper zero device if the connection is lost.
-// If a reconnect fails, it will indefinitely try again after 1 second.
+// Failed attempts are retried according to the reconnect policy. If the policy gives up,
+// the flipper zero is closed and Err reports why.
 func (f *FlipperZero) reconnLoop() {
 	for {
 		select {
@@ -140,13 +195,10 @@ func (f *FlipperZero) reconnLoop() {
 			}
 			f.connecting = true
 			f.SetFlipper(nil)
-			for {
-				if err := f.reconnect(); err != nil {
-					f.logger.Printf("could not reconnect: %v", err)
-					time.Sleep(time.Second)
-					continue
-				}
-				break
+			if err := f.retryReconnect(); err != nil {
+				f.logger.Println(err)
+				f.fail(err)
+				return
 			}
 			f.connecting = false
 		case <-f.ctx.Done():
@@ -154,3 +206,26 @@ func (f *FlipperZero) reconnLoop() {
 		}
 	}
 }
+
+// retryReconnect reconnects until it succeeds, the policy gives up or the flipper zero is closed.
+func (f *FlipperZero) retryReconnect() error {
+	start := time.Now()
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		f.emit(Event{State: StateReconnecting, Attempt: attempt, Err: lastErr})
+		if lastErr = f.reconnect(attempt); lastErr == nil {
+			return nil
+		}
+		f.logger.Printf("could not reconnect: %v", lastErr)
+		if f.policy.exhausted(attempt, start) {
+			return fmt.Errorf("%w after %d attempts: %w", ErrGaveUp, attempt, lastErr)
+		}
+		t := time.NewTimer(f.policy.backoff(attempt))
+		select {
+		case <-t.C:
+		case <-f.ctx.Done():
+			t.Stop()
+			return nil
+		}
+	}
+}
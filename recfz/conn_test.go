@@ -0,0 +1,225 @@
+package recfz_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/internal/race"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+// testTimeout is the time a test waits for a state. Lost connections are only detected every 2 seconds.
+const testTimeout = 10 * time.Second
+
+// newFlipper returns a flipper zero using the transport, which is closed when the test ends.
+func newFlipper(t *testing.T, tr recfz.Transport, opts ...recfz.Opts) *recfz.FlipperZero {
+	t.Helper()
+	opts = append([]recfz.Opts{recfz.WithTransport(tr), recfz.WithLogger(log.New(io.Discard, "", 0))}, opts...)
+	f, err := recfz.NewFlipperZero(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(f.Close)
+	return f
+}
+
+// connectFake connects a flipper zero to a new fake device.
+func connectFake(t *testing.T, opts ...recfz.Opts) (*recfz.FlipperZero, *fakefz.Device) {
+	t.Helper()
+	race.SkipGoFlipper(t)
+	d := fakefz.NewDevice()
+	t.Cleanup(d.Close)
+	f := newFlipper(t, d.Transport(), opts...)
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return f, d
+}
+
+// waitForState reads the events until the given state is received and returns the event.
+func waitForState(t *testing.T, sub *recfz.Subscription, state recfz.State) recfz.Event {
+	t.Helper()
+	timeout := time.After(testTimeout)
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				t.Fatalf("subscription closed while waiting for %s", state)
+			}
+			if e.State == state {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", state)
+		}
+	}
+}
+
+// scriptedTransport serves every connection with a function, e.g. to answer the handshake incorrectly.
+type scriptedTransport struct {
+	serve func(conn net.Conn)
+}
+
+func (t *scriptedTransport) Dial(_ context.Context) (recfz.Conn, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		t.serve(server)
+	}()
+	return &pipeConn{Conn: client}, nil
+}
+
+func (t *scriptedTransport) String() string { return "scripted" }
+
+type pipeConn struct {
+	net.Conn
+}
+
+func (c *pipeConn) Alive() error { return nil }
+
+func TestConnect(t *testing.T) {
+	race.SkipGoFlipper(t)
+	d := fakefz.NewDevice(fakefz.WithName("Testa"))
+	t.Cleanup(d.Close)
+	f := newFlipper(t, d.Transport())
+	sub := f.Subscribe()
+	defer sub.Close()
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, sub, recfz.StateConnecting)
+	waitForState(t, sub, recfz.StateHandshaking)
+	e := waitForState(t, sub, recfz.StateConnected)
+	if e.Info["hardware_name"] != "Testa" {
+		t.Fatalf("expected the device info of the fake, got %v", e.Info)
+	}
+	if !f.Connected() {
+		t.Fatal("expected the flipper to be connected")
+	}
+	if f.Port() != "fake://Testa" {
+		t.Fatalf("unexpected port %q", f.Port())
+	}
+}
+
+func TestConnectOffline(t *testing.T) {
+	d := fakefz.NewDevice()
+	t.Cleanup(d.Close)
+	d.SetOffline(true)
+	f := newFlipper(t, d.Transport())
+
+	if err := f.Connect(); !errors.Is(err, fakefz.ErrOffline) {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+	if e := f.State(); e.State != recfz.StateDisconnected || e.Err == nil {
+		t.Fatalf("expected a disconnected state with the error, got %s", e)
+	}
+}
+
+func TestHandshakeRejected(t *testing.T) {
+	f := newFlipper(t, &scriptedTransport{serve: func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		conn.Write([]byte("\r\n\r\n>: "))
+		br.ReadString('\r')
+		conn.Write([]byte("\r\ncommand not found\r"))
+		io.Copy(io.Discard, br)
+	}})
+
+	err := f.Connect()
+	if err == nil || f.Connected() {
+		t.Fatal("expected the handshake to fail")
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	// the device never shows the prompt
+	f := newFlipper(t, &scriptedTransport{serve: func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	}}, recfz.WithReconnectPolicy(recfz.ReconnectPolicy{HandshakeTimeout: 100 * time.Millisecond}))
+
+	start := time.Now()
+	err := f.Connect()
+	if err == nil {
+		t.Fatal("expected the handshake to time out")
+	}
+	if time.Since(start) > testTimeout {
+		t.Fatalf("the handshake took %s", time.Since(start))
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	f, d := connectFake(t, recfz.WithReconnectPolicy(recfz.ReconnectPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxAttempts:    5,
+	}))
+	sub := f.Subscribe()
+	defer sub.Close()
+
+	d.Disconnect()
+	waitForState(t, sub, recfz.StateLost)
+	if e := waitForState(t, sub, recfz.StateReconnecting); e.Attempt != 1 {
+		t.Fatalf("expected the first attempt, got %s", e)
+	}
+	waitForState(t, sub, recfz.StateConnected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if _, err := f.DeviceInfo(ctx); err != nil {
+		t.Fatalf("rpc after reconnect: %v", err)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	f, d := connectFake(t, recfz.WithReconnectPolicy(recfz.ReconnectPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxAttempts:    3,
+	}))
+	sub := f.Subscribe()
+	defer sub.Close()
+
+	d.SetOffline(true)
+	e := waitForState(t, sub, recfz.StateClosed)
+	if !errors.Is(e.Err, recfz.ErrGaveUp) {
+		t.Fatalf("expected ErrGaveUp in the closed event, got %v", e.Err)
+	}
+	select {
+	case <-f.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("the flipper wasn't closed")
+	}
+	if err := f.Err(); !errors.Is(err, recfz.ErrGaveUp) || !errors.Is(err, fakefz.ErrOffline) {
+		t.Fatalf("expected ErrGaveUp wrapping the last error, got %v", err)
+	}
+
+	// rpc calls fail right away instead of waiting for a connection
+	if _, err := f.DeviceInfo(context.Background()); !errors.Is(err, recfz.ErrClosed) || !errors.Is(err, recfz.ErrGaveUp) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestWaitForDevice(t *testing.T) {
+	race.SkipGoFlipper(t)
+	d := fakefz.NewDevice()
+	t.Cleanup(d.Close)
+	d.SetOffline(true)
+	f := newFlipper(t, d.Transport(), recfz.WithWaitForDevice(10*time.Millisecond))
+	sub := f.Subscribe()
+	defer sub.Close()
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, sub, recfz.StateWaiting)
+	if f.Connected() {
+		t.Fatal("connected to an offline device")
+	}
+	d.SetOffline(false)
+	waitForState(t, sub, recfz.StateConnected)
+}
@@ -0,0 +1,88 @@
+package recfz
+
+import (
+	"strings"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// usbSerialPrefix is the prefix of the usb serial number of every flipper zero.
+// The rest of the serial number is the name of the device.
+const usbSerialPrefix = "flip_"
+
+// Device describes a flipper zero attached via usb.
+type Device struct {
+	// Port is the serial port, e.g. /dev/ttyACM0.
+	Port string
+	// SerialNumber is the usb serial number, e.g. flip_Fztea.
+	SerialNumber string
+	// Name is the name of the flipper zero, e.g. Fztea.
+	Name string
+}
+
+// Selector selects a single device if multiple flipper zeros are attached.
+// Empty fields match every device.
+type Selector struct {
+	SerialNumber string
+	Name         string
+}
+
+// WithSerialNumber selects the flipper zero with the given usb serial number.
+// It is only used if the port is autodetected.
+func WithSerialNumber(serialNumber string) Opts {
+	return func(f *FlipperZero) {
+		f.selector.SerialNumber = serialNumber
+	}
+}
+
+// WithDeviceName selects the flipper zero with the given name (case insensitive).
+// It is only used if the port is autodetected.
+func WithDeviceName(name string) Opts {
+	return func(f *FlipperZero) {
+		f.selector.Name = name
+	}
+}
+
+// Matches returns true if the device is selected.
+func (s Selector) Matches(d Device) bool {
+	if s.SerialNumber != "" && s.SerialNumber != d.SerialNumber {
+		return false
+	}
+	if s.Name != "" && !strings.EqualFold(s.Name, d.Name) {
+		return false
+	}
+	return true
+}
+
+// IsZero returns true if the selector matches every device.
+func (s Selector) IsZero() bool {
+	return s == Selector{}
+}
+
+// ListDevices returns all flipper zeros attached via usb.
+func ListDevices() ([]Device, error) {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	for _, p := range ports {
+		if p.PID != flipperPid || p.VID != flipperVid {
+			continue
+		}
+		devices = append(devices, Device{
+			Port:         p.Name,
+			SerialNumber: p.SerialNumber,
+			Name:         deviceName(p),
+		})
+	}
+	return devices, nil
+}
+
+// deviceName derives the name of the flipper zero from its usb descriptors.
+func deviceName(p *enumerator.PortDetails) string {
+	if strings.HasPrefix(p.SerialNumber, usbSerialPrefix) {
+		return strings.TrimPrefix(p.SerialNumber, usbSerialPrefix)
+	}
+	return strings.TrimPrefix(p.Product, "Flipper ")
+}
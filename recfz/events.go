@@ -0,0 +1,175 @@
+package recfz
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// eventBufferSize is the number of events buffered per subscriber.
+// If a subscriber doesn't keep up, the oldest events are dropped.
+const eventBufferSize = 16
+
+// State represents the connection state of the flipper zero.
+type State int
+
+const (
+	// StateDisconnected is the initial state before Connect was called.
+	StateDisconnected State = iota
+	// StateConnecting is emitted when a connection is opened.
+	StateConnecting
+	// StateHandshaking is emitted when the connection is open and the rpc session is being started.
+	StateHandshaking
+	// StateConnected is emitted when the rpc session is up and running.
+	StateConnected
+	// StateLost is emitted when an established connection was lost.
+	StateLost
+	// StateReconnecting is emitted before every reconnect attempt.
+	StateReconnecting
+	// StateClosed is emitted when the flipper zero was closed. No events follow.
+	StateClosed
+	// StateWaiting is emitted while waiting for the device to show up.
+	StateWaiting
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateHandshaking:
+		return "handshaking"
+	case StateConnected:
+		return "connected"
+	case StateLost:
+		return "lost"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	case StateWaiting:
+		return "waiting"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Event describes a change of the connection state.
+type Event struct {
+	State State
+	Time  time.Time
+	// Err is the reason why the connection was lost or why the last reconnect attempt failed.
+	Err error
+	// Attempt is the number of the current reconnect attempt, starting at 1.
+	Attempt int
+	// Info holds the device information reported by the flipper once connected.
+	Info map[string]string
+}
+
+// String returns a human readable description of the event.
+func (e Event) String() string {
+	s := e.State.String()
+	if e.Attempt > 0 {
+		s += fmt.Sprintf(" (attempt %d)", e.Attempt)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Subscription receives the state events of a flipper zero.
+type Subscription struct {
+	f      *FlipperZero
+	ch     chan Event
+	closed bool
+}
+
+// events keeps track of all subscribers.
+type events struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	last Event
+	done bool
+}
+
+// Subscribe returns a new subscription to the state events.
+// The subscription immediately receives the current state.
+func (f *FlipperZero) Subscribe() *Subscription {
+	f.events.mu.Lock()
+	defer f.events.mu.Unlock()
+	s := &Subscription{
+		f:  f,
+		ch: make(chan Event, eventBufferSize),
+	}
+	s.ch <- f.events.last
+	if f.events.done {
+		s.closed = true
+		close(s.ch)
+		return s
+	}
+	if f.events.subs == nil {
+		f.events.subs = make(map[*Subscription]struct{})
+	}
+	f.events.subs[s] = struct{}{}
+	return s
+}
+
+// State returns the current connection state.
+func (f *FlipperZero) State() Event {
+	f.events.mu.Lock()
+	defer f.events.mu.Unlock()
+	return f.events.last
+}
+
+// Events returns the channel on which the events are received.
+// The channel is closed after the StateClosed event or when the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close closes the subscription.
+func (s *Subscription) Close() {
+	s.f.events.mu.Lock()
+	defer s.f.events.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.f.events.subs, s)
+	close(s.ch)
+}
+
+// emit sends an event to all subscribers.
+// If the event is StateClosed, all subscriptions are closed afterwards.
+func (f *FlipperZero) emit(e Event) {
+	f.events.mu.Lock()
+	defer f.events.mu.Unlock()
+	if f.events.done {
+		return
+	}
+	e.Time = time.Now()
+	f.events.last = e
+	for s := range f.events.subs {
+		// drop the oldest event if the subscriber doesn't keep up
+		select {
+		case s.ch <- e:
+			continue
+		default:
+		}
+		select {
+		case <-s.ch:
+		default:
+		}
+		s.ch <- e
+	}
+	if e.State == StateClosed {
+		f.events.done = true
+		for s := range f.events.subs {
+			s.closed = true
+			close(s.ch)
+		}
+		f.events.subs = nil
+	}
+}
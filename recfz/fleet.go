@@ -0,0 +1,93 @@
+package recfz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+)
+
+// Fleet sends the same input to multiple flipper zeros at once.
+type Fleet struct {
+	flippers      []*FlipperZero
+	settle        time.Duration
+	settleTimeout time.Duration
+}
+
+// FleetOpts represents an optional configuration for the fleet.
+type FleetOpts func(fl *Fleet)
+
+// WithSettle makes the fleet wait after every input until the screens of all flipper zeros
+// didn't change for the given duration. It gives up waiting after the timeout.
+func WithSettle(quiet, timeout time.Duration) FleetOpts {
+	return func(fl *Fleet) {
+		fl.settle = quiet
+		fl.settleTimeout = timeout
+	}
+}
+
+// NewFleet creates a new fleet of flipper zeros.
+func NewFleet(flippers []*FlipperZero, opts ...FleetOpts) *Fleet {
+	fl := &Fleet{flippers: flippers}
+	for _, opt := range opts {
+		opt(fl)
+	}
+	return fl
+}
+
+// Flippers returns the flipper zeros of the fleet.
+func (fl *Fleet) Flippers() []*FlipperZero {
+	return fl.flippers
+}
+
+// Result is the outcome of an input sent to a single flipper zero.
+type Result struct {
+	Flipper *FlipperZero
+	// Err is the error returned while sending the input.
+	Err error
+	// Settled is true if the screen settled after the input. It is always false if the fleet doesn't wait for the screens.
+	Settled bool
+	// Duration is the time it took to send the input and wait for the screen.
+	Duration time.Duration
+}
+
+// SendShortPress sends a short press to all flipper zeros in parallel.
+// The results are in the same order as the flipper zeros of the fleet.
+func (fl *Fleet) SendShortPress(ctx context.Context, event flipper.InputKey) []Result {
+	return fl.send(ctx, func(f *FlipperZero) error { return f.SendShortPress(event) })
+}
+
+// SendLongPress sends a long press to all flipper zeros in parallel.
+// The results are in the same order as the flipper zeros of the fleet.
+func (fl *Fleet) SendLongPress(ctx context.Context, event flipper.InputKey) []Result {
+	return fl.send(ctx, func(f *FlipperZero) error { return f.SendLongPress(event) })
+}
+
+func (fl *Fleet) send(ctx context.Context, fn func(f *FlipperZero) error) []Result {
+	results := make([]Result, len(fl.flippers))
+	var wg sync.WaitGroup
+	for i, f := range fl.flippers {
+		wg.Add(1)
+		go func(i int, f *FlipperZero) {
+			defer wg.Done()
+			start := time.Now()
+			results[i] = Result{Flipper: f, Err: fn(f)}
+			if results[i].Err == nil && fl.settle > 0 {
+				results[i].Settled = fl.waitForSettle(ctx, f) == nil
+			}
+			results[i].Duration = time.Since(start)
+		}(i, f)
+	}
+	wg.Wait()
+	return results
+}
+
+func (fl *Fleet) waitForSettle(ctx context.Context, f *FlipperZero) error {
+	if fl.settleTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fl.settleTimeout)
+		defer cancel()
+	}
+	return f.WaitForScreenSettle(ctx, fl.settle)
+}
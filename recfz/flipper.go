@@ -1,48 +1,120 @@
 package recfz
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/flipperdevices/go-flipper"
 )
 
+// ErrNotConnected is returned if the flipper zero is not connected.
+var ErrNotConnected = errors.New("flipper is not connected")
+
+// screen keeps track of the changes of the screen.
+// It has its own mutex because frames are received while input events are sent.
+type screen struct {
+	mu         sync.Mutex
+	last       []byte
+	lastChange time.Time
+}
+
 // startScreenStream starts a screen stream from the flipper zero device.
-// It triggers a callback function for every new screen frame.
+// It triggers the callback function, if set, for every new screen frame.
 func (f *FlipperZero) startScreenStream() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	if f.streamScreenCallback == nil {
-		return errors.New("no stream screen callback set")
-	}
-	if err := f.flipper.Gui.StartScreenStream(f.streamScreenCallback); err != nil {
+	if err := f.flipper.Gui.StartScreenStream(f.onScreenFrame); err != nil {
 		return err
 	}
 	f.logger.Println("started screen streaming...")
 	return nil
 }
 
-// SendShortPress sends a short press event to the flipper zero device.
-// If the flipper zero device is not connected, it will do nothing.
-func (f *FlipperZero) SendShortPress(event flipper.InputKey) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	if f.flipper == nil {
-		return
+// onScreenFrame records changes of the screen and passes the frame to the callback.
+func (f *FlipperZero) onScreenFrame(frame flipper.ScreenFrame) {
+	f.screen.mu.Lock()
+	if !bytes.Equal(f.screen.last, frame.Bytes()) {
+		f.screen.last = append(f.screen.last[:0], frame.Bytes()...)
+		f.screen.lastChange = time.Now()
+	}
+	f.screen.mu.Unlock()
+	if f.streamScreenCallback != nil {
+		f.streamScreenCallback(frame)
+	}
+}
+
+// LastScreenChange returns the time the screen changed the last time.
+func (f *FlipperZero) LastScreenChange() time.Time {
+	f.screen.mu.Lock()
+	defer f.screen.mu.Unlock()
+	return f.screen.lastChange
+}
+
+// WaitForScreenSettle blocks until the screen didn't change for the given duration, counting from now at the earliest.
+// It returns the error of the context if it is done first.
+// Without the screen stream, the screen never changes and it returns right away.
+func (f *FlipperZero) WaitForScreenSettle(ctx context.Context, quiet time.Duration) error {
+	if f.noScreenStream {
+		return ctx.Err()
+	}
+	since := time.Now()
+	for {
+		last := f.LastScreenChange()
+		if last.Before(since) {
+			last = since
+		}
+		wait := time.Until(last.Add(quiet))
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypePress)   //nolint:errcheck
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypeShort)   //nolint:errcheck
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypeRelease) //nolint:errcheck
+}
+
+// SendShortPress sends a short press event to the flipper zero device.
+// If the flipper zero device is not connected, it returns ErrNotConnected.
+func (f *FlipperZero) SendShortPress(event flipper.InputKey) error {
+	return f.sendPress(event, flipper.InputTypeShort)
 }
 
 // SendLongPress sends a long press event to the flipper zero device.
-// If the flipper zero device is not connected, it will do nothing.
-func (f *FlipperZero) SendLongPress(event flipper.InputKey) {
+// If the flipper zero device is not connected, it returns ErrNotConnected.
+func (f *FlipperZero) SendLongPress(event flipper.InputKey) error {
+	return f.sendPress(event, flipper.InputTypeLong)
+}
+
+// sendPress sends a press, the given input type and a release event.
+// The release is always sent, so the key doesn't get stuck if the input type failed.
+func (f *FlipperZero) sendPress(event flipper.InputKey, typ flipper.InputType) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	if f.flipper == nil {
-		return
+		return ErrNotConnected
 	}
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypePress)   //nolint:errcheck
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypeLong)    //nolint:errcheck
-	f.flipper.Gui.SendInputEvent(event, flipper.InputTypeRelease) //nolint:errcheck
+	if err := f.flipper.Gui.SendInputEvent(event, flipper.InputTypePress); err != nil {
+		return err
+	}
+	err := f.flipper.Gui.SendInputEvent(event, typ)
+	if rerr := f.flipper.Gui.SendInputEvent(event, flipper.InputTypeRelease); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// DeviceInfo returns the device information reported by the flipper zero, e.g. the hardware name and firmware version.
+func (f *FlipperZero) DeviceInfo(ctx context.Context) (map[string]string, error) {
+	var info map[string]string
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		var err error
+		info, err = fl.System.DeviceInfo()
+		return err
+	})
+	return info, err
 }
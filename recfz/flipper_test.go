@@ -0,0 +1,21 @@
+package recfz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jon4hz/fztea/recfz"
+)
+
+func TestWaitForScreenSettleWithoutStream(t *testing.T) {
+	f, _ := connectFake(t, recfz.WithoutScreenStream())
+
+	// the screen never changes without the stream, so there is nothing to wait for
+	start := time.Now()
+	if err := f.WaitForScreenSettle(testContext(t), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("waited %s for the screen to settle", elapsed)
+	}
+}
@@ -0,0 +1,195 @@
+package recfz
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/flipperdevices/go-flipper"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// storages are the storages of the flipper zero reported by Info.
+var storages = []string{"/ext", "/int"}
+
+// field numbers of the power info messages in PB.Main.
+const (
+	fieldPowerInfoRequest  protowire.Number = 44
+	fieldPowerInfoResponse protowire.Number = 45
+)
+
+// Info summarizes the flipper zero. It's queried over rpc without interrupting the screen stream.
+type Info struct {
+	// Properties are the device info properties, e.g. hardware_name or firmware_version.
+	Properties map[string]string `json:"properties"`
+	Storage    []StorageSpace    `json:"storage"`
+	// Power is nil if the battery couldn't be queried, PowerErr tells why.
+	Power    *Power `json:"power,omitempty"`
+	PowerErr string `json:"power_error,omitempty"`
+}
+
+// Power is the battery status of the flipper zero.
+type Power struct {
+	// Charge is the charge level in percent.
+	Charge int `json:"charge"`
+	// Voltage is the battery voltage in volts.
+	Voltage float64 `json:"voltage"`
+	// Temperature is the battery temperature in °C.
+	Temperature int `json:"temperature"`
+	// State is the charge state, e.g. charging, discharging or charged.
+	State string `json:"state"`
+}
+
+// StorageSpace is the capacity of a storage. Err is set if the storage couldn't be queried, e.g. if there is no sd card.
+type StorageSpace struct {
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+	Err   string `json:"error,omitempty"`
+}
+
+// Info queries the device info and the space of the storages.
+func (f *FlipperZero) Info(ctx context.Context) (*Info, error) {
+	props, err := f.DeviceInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Properties: props}
+	for _, p := range storages {
+		s := StorageSpace{Path: p}
+		s.Total, s.Free, err = f.StorageInfo(ctx, p)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			s.Err = err.Error()
+		}
+		info.Storage = append(info.Storage, s)
+	}
+	info.Power, err = f.PowerInfo(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		info.PowerErr = err.Error()
+	}
+	return info, nil
+}
+
+// PowerInfo queries the battery status. The rpc client doesn't support it, so it's sent as a raw call.
+// It returns ErrNotSupported if the firmware is too old.
+func (f *FlipperZero) PowerInfo(ctx context.Context) (*Power, error) {
+	props := make(map[string]string)
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		res, err := f.rawCall(fl, fieldPowerInfoRequest, nil)
+		if err != nil {
+			return err
+		}
+		for _, r := range res {
+			if r.content != fieldPowerInfoResponse {
+				return fmt.Errorf("unexpected response %d to power info", r.content)
+			}
+			k, v, err := decodeKeyValue(r.msg)
+			if err != nil {
+				return err
+			}
+			props[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("power info: %w", err)
+	}
+	return parsePower(props)
+}
+
+// parsePower reads the battery status from the power info properties.
+// The voltage is reported in millivolts.
+func parsePower(props map[string]string) (*Power, error) {
+	var p Power
+	var err error
+	if p.Charge, err = strconv.Atoi(props["charge_level"]); err != nil {
+		return nil, fmt.Errorf("invalid charge level: %w", err)
+	}
+	if mv, err := strconv.Atoi(props["battery_voltage"]); err == nil {
+		p.Voltage = float64(mv) / 1000
+	}
+	p.Temperature, _ = strconv.Atoi(props["battery_temp"])
+	p.State = props["charge_state"]
+	return &p, nil
+}
+
+// decodeKeyValue decodes a PowerInfoResponse, which has a key and a value like the device info.
+func decodeKeyValue(b []byte) (key, value string, err error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		} else {
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				key = string(v)
+			case 2:
+				value = string(v)
+			}
+		}
+		if n < 0 {
+			return "", "", protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return key, value, nil
+}
+
+// Charging returns true while the battery is charged.
+func (p *Power) Charging() bool {
+	return p.State == "charging"
+}
+
+// String returns a short description, e.g. 87% discharging, 3.95 V, 26 °C.
+func (p *Power) String() string {
+	s := fmt.Sprintf("%d%%", p.Charge)
+	if p.State != "" {
+		s += " " + p.State
+	}
+	if p.Voltage > 0 {
+		s += fmt.Sprintf(", %.2f V", p.Voltage)
+	}
+	return s + fmt.Sprintf(", %d °C", p.Temperature)
+}
+
+// Name returns the name of the flipper zero.
+func (i *Info) Name() string {
+	return i.Properties["hardware_name"]
+}
+
+// Firmware returns the firmware version including the branch and commit, e.g. 0.98.3 (release@a1b2c3d).
+func (i *Info) Firmware() string {
+	v := i.Properties["firmware_version"]
+	var origin []string
+	if b := i.Properties["firmware_branch"]; b != "" {
+		origin = append(origin, b)
+	}
+	if c := i.Properties["firmware_commit"]; c != "" {
+		origin = append(origin, c)
+	}
+	if len(origin) > 0 {
+		v += " (" + strings.Join(origin, "@") + ")"
+	}
+	return v
+}
+
+// Protobuf returns the version of the rpc protocol, e.g. 0.24.
+func (i *Info) Protobuf() string {
+	if i.Properties["protobuf_version_major"] == "" {
+		return ""
+	}
+	return i.Properties["protobuf_version_major"] + "." + i.Properties["protobuf_version_minor"]
+}
@@ -0,0 +1,68 @@
+package recfz_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/internal/race"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+func TestInfo(t *testing.T) {
+	f, _ := connectFake(t)
+	ctx := testContext(t)
+
+	info, err := f.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "Fztea" || info.Firmware() != "0.0.0 (fake@fztea)" || info.Protobuf() != "0.6" {
+		t.Fatalf("unexpected device info %v", info.Properties)
+	}
+	if len(info.Storage) != 2 || info.Storage[0].Path != "/ext" || info.Storage[0].Total == 0 || info.Storage[0].Err != "" {
+		t.Fatalf("unexpected storage %+v", info.Storage)
+	}
+	if info.Power == nil || info.PowerErr != "" {
+		t.Fatalf("expected the battery status: %s", info.PowerErr)
+	}
+	if p := info.Power; p.Charge != 87 || p.State != "discharging" || p.Charging() || p.Voltage < 3 || p.Voltage > 4.5 || p.Temperature != 26 {
+		t.Fatalf("unexpected battery status %+v", p)
+	}
+}
+
+func TestPowerInfo(t *testing.T) {
+	race.SkipGoFlipper(t)
+	d := fakefz.NewDevice(fakefz.WithBattery(42, true))
+	t.Cleanup(d.Close)
+	f := newFlipper(t, d.Transport())
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := testContext(t)
+
+	// the responses are taken out of the stream of the rpc client, which keeps working in between
+	for range 3 {
+		p, err := f.PowerInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Charge != 42 || !p.Charging() {
+			t.Fatalf("unexpected battery status %+v", p)
+		}
+		if p.String() != "42% charging, 3.83 V, 26 °C" {
+			t.Fatalf("unexpected description %q", p)
+		}
+		if _, err := f.DeviceInfo(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPowerInfoClosed(t *testing.T) {
+	f, _ := connectFake(t)
+	f.Close()
+	if _, err := f.PowerInfo(testContext(t)); !errors.Is(err, recfz.ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package recfz
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrGaveUp is returned by Err if the reconnect policy gave up on the flipper zero.
+var ErrGaveUp = errors.New("gave up reconnecting")
+
+// ReconnectPolicy controls how the flipper zero is reconnected once the connection is lost.
+type ReconnectPolicy struct {
+	// InitialBackoff is the delay after the first failed attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every failed attempt. Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter randomizes the delay by the given fraction, e.g. 0.2 means ±20%.
+	Jitter float64
+	// MaxAttempts is the maximum number of reconnect attempts. Zero means unlimited.
+	MaxAttempts int
+	// MaxElapsed is the maximum time spent reconnecting. Zero means unlimited.
+	MaxElapsed time.Duration
+	// HandshakeTimeout limits the time to start the rpc session. It is also used as timeout for rpc calls.
+	HandshakeTimeout time.Duration
+}
+
+// DefaultReconnectPolicy returns the policy used if none is set.
+// It retries every second forever.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialBackoff:   time.Second,
+		MaxBackoff:       time.Second,
+		Multiplier:       1,
+		HandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// WithReconnectPolicy sets the reconnect policy for the flipper zero.
+func WithReconnectPolicy(p ReconnectPolicy) Opts {
+	return func(f *FlipperZero) {
+		f.policy = p
+	}
+}
+
+// backoff returns the delay after the given failed attempt, starting at 1.
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	mult := math.Max(p.Multiplier, 1)
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		d = math.Min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec
+	}
+	return time.Duration(d)
+}
+
+// exhausted returns true if no further attempt is allowed.
+func (p ReconnectPolicy) exhausted(attempts int, start time.Time) bool {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return true
+	}
+	if p.MaxElapsed > 0 && time.Since(start) >= p.MaxElapsed {
+		return true
+	}
+	return false
+}
+
+// handshakeTimeout returns the configured handshake timeout or the default one.
+func (p ReconnectPolicy) handshakeTimeout() time.Duration {
+	if p.HandshakeTimeout <= 0 {
+		return DefaultReconnectPolicy().HandshakeTimeout
+	}
+	return p.HandshakeTimeout
+}
@@ -0,0 +1,228 @@
+package recfz
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// rawCommandID is the command id of the requests the rpc client doesn't support, which are sent by recfz itself.
+// The rpc client counts its command ids up from 1, so it doesn't reach it.
+const rawCommandID = math.MaxUint32
+
+// field numbers of the PB.Main message.
+// The generated protobuf code of go-flipper is internal, so the messages are encoded by hand.
+const (
+	fieldCommandID     protowire.Number = 1
+	fieldCommandStatus protowire.Number = 2
+	fieldHasNext       protowire.Number = 3
+)
+
+// rawResponseBuffer is the number of responses buffered until the raw call reads them.
+const rawResponseBuffer = 64
+
+// statusNames are the names of the status codes, as reported by the rpc client for its own calls.
+var statusNames = map[uint64]string{
+	1:  "ERROR",
+	2:  "ERROR_DECODE",
+	3:  "ERROR_NOT_IMPLEMENTED",
+	4:  "ERROR_BUSY",
+	5:  "ERROR_STORAGE_NOT_READY",
+	6:  "ERROR_STORAGE_EXIST",
+	7:  "ERROR_STORAGE_NOT_EXIST",
+	8:  "ERROR_STORAGE_INVALID_PARAMETER",
+	9:  "ERROR_STORAGE_DENIED",
+	10: "ERROR_STORAGE_INVALID_NAME",
+	11: "ERROR_STORAGE_INTERNAL",
+	12: "ERROR_STORAGE_NOT_IMPLEMENTED",
+	13: "ERROR_STORAGE_ALREADY_OPEN",
+	14: "ERROR_CONTINUOUS_COMMAND_INTERRUPTED",
+	15: "ERROR_INVALID_PARAMETERS",
+	16: "ERROR_APP_CANT_START",
+	17: "ERROR_APP_SYSTEM_LOCKED",
+	18: "ERROR_STORAGE_DIR_NOT_EMPTY",
+}
+
+// rawResponse is a response to a raw call.
+type rawResponse struct {
+	status  uint64
+	hasNext bool
+	// content is the field number of the content and msg the encoded content.
+	content protowire.Number
+	msg     []byte
+}
+
+// tap reads the messages of the flipper zero for the rpc client, but takes the responses to raw calls out.
+// The rpc client drops the connection if it receives a response it didn't ask for.
+type tap struct {
+	r   *bufio.Reader
+	buf []byte
+	// responses receives the responses to raw calls.
+	responses chan rawResponse
+	// done is closed once reading failed.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newTap(r io.Reader) *tap {
+	return &tap{
+		r:         bufio.NewReader(r),
+		responses: make(chan rawResponse, rawResponseBuffer),
+		done:      make(chan struct{}),
+	}
+}
+
+// Read passes the messages which aren't responses to raw calls to the rpc client.
+func (t *tap) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		msg, err := t.next()
+		if err != nil {
+			t.doneOnce.Do(func() { close(t.done) })
+			return 0, err
+		}
+		if res, ok := decodeRawResponse(msg); ok {
+			// a response nobody waits for anymore is dropped
+			select {
+			case t.responses <- res:
+			default:
+			}
+			continue
+		}
+		t.buf = protowire.AppendBytes(nil, msg)
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// next reads a single length delimited message.
+func (t *tap) next() ([]byte, error) {
+	size, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(t.r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// drain drops the responses of earlier calls, which timed out.
+func (t *tap) drain() {
+	for {
+		select {
+		case <-t.responses:
+		default:
+			return
+		}
+	}
+}
+
+// decodeRawResponse decodes a PB.Main message if it's a response to a raw call.
+func decodeRawResponse(b []byte) (rawResponse, bool) {
+	var (
+		res rawResponse
+		id  uint64
+	)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return rawResponse{}, false
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			switch num {
+			case fieldCommandID:
+				id = v
+			case fieldCommandStatus:
+				res.status = v
+			case fieldHasNext:
+				res.hasNext = v != 0
+			}
+		case protowire.BytesType:
+			res.content = num
+			res.msg, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return rawResponse{}, false
+		}
+		b = b[n:]
+	}
+	return res, id == rawCommandID
+}
+
+// rawCall sends a request the rpc client doesn't support and returns the responses.
+// It must be called inside rpc, which serializes it with the other calls.
+// Like the rpc client, it reports a failed status by its name.
+func (f *FlipperZero) rawCall(fl *flipper.Flipper, content protowire.Number, req []byte) ([]rawResponse, error) {
+	f.mu.Lock()
+	t := f.tap
+	current := f.flipper == fl
+	f.mu.Unlock()
+	if t == nil || !current {
+		return nil, ErrConnectionLost
+	}
+	t.drain()
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, fieldCommandID, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, rawCommandID)
+	msg = protowire.AppendTag(msg, content, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, req)
+	if err := f.sendRaw(fl, msg); err != nil {
+		return nil, err
+	}
+
+	timeout := f.policy.handshakeTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var res []rawResponse
+	for {
+		select {
+		case r := <-t.responses:
+			if r.status != 0 {
+				if name, ok := statusNames[r.status]; ok {
+					return nil, errors.New(name)
+				}
+				return nil, fmt.Errorf("command failed with status %d", r.status)
+			}
+			res = append(res, r)
+			if !r.hasNext {
+				return res, nil
+			}
+			timer.Reset(timeout)
+		case <-t.done:
+			return nil, ErrConnectionLost
+		case <-timer.C:
+			return nil, errors.New("request timeout")
+		case <-f.ctx.Done():
+			return nil, ErrClosed
+		}
+	}
+}
+
+// sendRaw writes an encoded PB.Main message to the connection of fl.
+// The lock keeps it from interleaving with input events, other rpc calls are serialized by rpc.
+func (f *FlipperZero) sendRaw(fl *flipper.Flipper, msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flipper != fl || f.conn == nil {
+		return ErrConnectionLost
+	}
+	_, err := f.conn.Write(protowire.AppendBytes(nil, msg))
+	return err
+}
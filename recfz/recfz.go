@@ -2,12 +2,11 @@ package recfz
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/flipperdevices/go-flipper"
-	"go.bug.st/serial"
 )
 
 const (
@@ -20,6 +19,7 @@ const (
 type Opts func(f *FlipperZero)
 
 // WithPort sets the port of the flipper zero.
+// Ports prefixed with tcp:// (e.g. tcp://lab-pi:3333) are reached over tcp.
 func WithPort(port string) Opts {
 	return func(f *FlipperZero) {
 		f.port = port
@@ -40,6 +40,14 @@ func WithStreamScreenCallback(cb func(frame flipper.ScreenFrame)) Opts {
 	}
 }
 
+// WithoutScreenStream disables the screen stream, e.g. to speed up file transfers.
+// The screen never changes without the stream, so WaitForScreenSettle returns right away.
+func WithoutScreenStream() Opts {
+	return func(f *FlipperZero) {
+		f.noScreenStream = true
+	}
+}
+
 // WithLogger sets the logger for the flipper zero.
 func WithLogger(l *log.Logger) Opts {
 	return func(f *FlipperZero) {
@@ -47,49 +55,71 @@ func WithLogger(l *log.Logger) Opts {
 	}
 }
 
+// WithWaitForDevice makes the flipper zero wait for the device instead of failing if it isn't available.
+// Connect returns immediately and the device is polled in the given interval until it shows up.
+func WithWaitForDevice(interval time.Duration) Opts {
+	return func(f *FlipperZero) {
+		f.waitInterval = interval
+	}
+}
+
 // FlipperZero represents the flipper zero device.
 type FlipperZero struct {
-	parentCtx            context.Context
-	ctx                  context.Context
-	cancel               context.CancelFunc
-	port                 string
-	conn                 serial.Port
+	parentCtx context.Context
+	ctx       context.Context
+	cancel    context.CancelFunc
+	port      string
+	selector  Selector
+	transport Transport
+	conn      Conn
+	// tap reads from conn for the rpc client and receives the responses to raw calls.
+	tap                  *tap
 	flipper              *flipper.Flipper
 	reconnCh             chan struct{}
 	connecting           bool
 	mu                   sync.Mutex
-	staticPort           bool
 	streamScreenCallback func(frame flipper.ScreenFrame)
+	screen               screen
 	logger               *log.Logger
 	isClosing            bool
+	events               events
+	policy               ReconnectPolicy
+	waitInterval         time.Duration
+	// err is the terminal error, set if the reconnect policy gave up.
+	err error
+	// rpcSem serializes the rpc calls, except for input events.
+	rpcSem         chan struct{}
+	noScreenStream bool
 }
 
 // NewFlipperZero creates a new flipper zero device.
-// If the port is not static, it will try to autodetect the flipper.
+// If no transport is set and the port is not static, it will try to autodetect the flipper,
+// unless it is configured to wait for the device.
 func NewFlipperZero(opts ...Opts) (*FlipperZero, error) {
 	f := &FlipperZero{
 		reconnCh:  make(chan struct{}),
 		logger:    log.Default(),
 		parentCtx: context.Background(),
+		policy:    DefaultReconnectPolicy(),
+		rpcSem:    make(chan struct{}, 1),
 	}
 	for _, opt := range opts {
 		opt(f)
 	}
 	f.ctx, f.cancel = context.WithCancel(f.parentCtx)
 
-	if f.port == "" {
-		p, err := f.autodetectFlipper()
+	if f.transport == nil {
+		t, err := newTransport(f.port, f.selector, f.logger, f.waitInterval > 0)
 		if err != nil {
-			return nil, fmt.Errorf("could not autodetect flipper: %w", err)
+			return nil, err
 		}
-		f.port = p
-	} else {
-		f.staticPort = true
+		f.transport = t
 	}
 	return f, nil
 }
 
 // Close closes the connection to the flipper zero.
+// All state subscriptions receive a StateClosed event and are closed afterwards.
 func (f *FlipperZero) Close() {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -99,7 +129,31 @@ func (f *FlipperZero) Close() {
 	f.isClosing = true
 	f.cancel()
 	close(f.reconnCh)
-	f.conn.Close()
+	if f.conn != nil {
+		f.conn.Close()
+	}
+	f.emit(Event{State: StateClosed, Err: f.err})
+}
+
+// fail closes the flipper zero with a terminal error.
+func (f *FlipperZero) fail(err error) {
+	f.mu.Lock()
+	f.err = err
+	f.mu.Unlock()
+	f.Close()
+}
+
+// Done returns a channel that is closed once the flipper zero is closed.
+func (f *FlipperZero) Done() <-chan struct{} {
+	return f.ctx.Done()
+}
+
+// Err returns the reason why the flipper zero was closed, e.g. ErrGaveUp if the reconnect policy gave up.
+// It returns nil if the flipper zero is still open or was closed by Close.
+func (f *FlipperZero) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
 }
 
 func (f *FlipperZero) getClosing() bool {
@@ -122,14 +176,14 @@ func (f *FlipperZero) SetFlipper(fz *flipper.Flipper) {
 	f.flipper = fz
 }
 
-// SetConn sets a serial connection to the flipper zero.
-func (f *FlipperZero) SetConn(c serial.Port) {
+// SetConn sets a connection to the flipper zero.
+func (f *FlipperZero) SetConn(c Conn) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.conn = c
 }
 
-func (f *FlipperZero) getConn() serial.Port {
+func (f *FlipperZero) getConn() Conn {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	return f.conn
@@ -141,7 +195,12 @@ func (f *FlipperZero) GetFlipper() (*flipper.Flipper, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	if f.flipper == nil {
-		return nil, fmt.Errorf("flipper is not connected")
+		return nil, ErrNotConnected
 	}
 	return f.flipper, nil
 }
+
+// Port returns a description of the port the flipper zero is connected to.
+func (f *FlipperZero) Port() string {
+	return f.transport.String()
+}
@@ -0,0 +1,361 @@
+package recfz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/flipperdevices/go-flipper"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	// ErrClosed is returned if the flipper zero was closed.
+	ErrClosed = errors.New("flipper is closed")
+	// ErrConnectionLost is returned if the connection was lost during an operation.
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrDirNotEmpty is returned if a directory can't be removed because it's not empty.
+	ErrDirNotEmpty = errors.New("directory not empty")
+	// ErrNotSupported is returned if the firmware of the flipper zero doesn't support a call.
+	ErrNotSupported = errors.New("not supported by the firmware")
+)
+
+// fieldStorageWriteRequest is the field number of the write request in PB.Main.
+const fieldStorageWriteRequest protowire.Number = 11
+
+// statusErrors maps the rpc status codes reported by go-flipper to errors.
+var statusErrors = map[string]error{
+	"ERROR_STORAGE_EXIST":             fs.ErrExist,
+	"ERROR_STORAGE_NOT_EXIST":         fs.ErrNotExist,
+	"ERROR_STORAGE_DENIED":            fs.ErrPermission,
+	"ERROR_STORAGE_INVALID_NAME":      fs.ErrInvalid,
+	"ERROR_STORAGE_INVALID_PARAMETER": fs.ErrInvalid,
+	"ERROR_STORAGE_DIR_NOT_EMPTY":     ErrDirNotEmpty,
+	"ERROR_NOT_IMPLEMENTED":           ErrNotSupported,
+	"ERROR_APP_SYSTEM_LOCKED":         ErrAppLocked,
+	"ERROR_APP_CANT_START":            ErrAppCantStart,
+}
+
+// Progress is called during a transfer with the number of bytes transferred so far and the total size.
+type Progress func(done, total int64)
+
+// FileInfo describes a file or directory on the flipper zero. It implements fs.FileInfo.
+// The flipper zero doesn't report modification times or permissions.
+type FileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func newFileInfo(f *flipper.File, name string) *FileInfo {
+	if f.Name != "" {
+		name = f.Name
+	}
+	return &FileInfo{
+		name: name,
+		size: int64(f.Size),
+		dir:  f.Type == flipper.FileTypeDir,
+	}
+}
+
+// Name returns the base name of the file.
+func (fi *FileInfo) Name() string { return fi.name }
+
+// Size returns the size of the file in bytes.
+func (fi *FileInfo) Size() int64 { return fi.size }
+
+// Mode returns fixed permissions, the flipper zero has no permissions.
+func (fi *FileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+// ModTime returns the zero time, the flipper zero doesn't report modification times.
+func (fi *FileInfo) ModTime() time.Time { return time.Time{} }
+
+// IsDir returns true if the file is a directory.
+func (fi *FileInfo) IsDir() bool { return fi.dir }
+
+// Sys returns nil.
+func (fi *FileInfo) Sys() any { return nil }
+
+// FormatSize formats a size in bytes for humans, e.g. 1.2 KiB.
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// List returns the files and directories in a directory.
+func (f *FlipperZero) List(ctx context.Context, p string) ([]fs.FileInfo, error) {
+	var files []fs.FileInfo
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		res, err := fl.Storage.List(p)
+		for _, file := range res {
+			files = append(files, newFileInfo(file, ""))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "list", Path: p, Err: err}
+	}
+	return files, nil
+}
+
+// Stat returns information about a file or directory.
+func (f *FlipperZero) Stat(ctx context.Context, p string) (fs.FileInfo, error) {
+	var fi fs.FileInfo
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		file, err := fl.Storage.Stat(p)
+		if err != nil {
+			return err
+		}
+		fi = newFileInfo(file, path.Base(p))
+		return nil
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+	return fi, nil
+}
+
+// ReadFile reads a file and writes its content to w. It returns the number of bytes written.
+// The file is read completely before it's written to w, so w doesn't receive partial content if the connection is lost.
+func (f *FlipperZero) ReadFile(ctx context.Context, p string, w io.Writer, progress Progress) (int64, error) {
+	var total int64
+	if progress != nil {
+		// the size is only needed to report the progress
+		fi, err := f.Stat(ctx, p)
+		if err != nil {
+			return 0, err
+		}
+		if fi.IsDir() {
+			return 0, &fs.PathError{Op: "read", Path: p, Err: fs.ErrInvalid}
+		}
+		total = fi.Size()
+	}
+
+	var data []byte
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		var err error
+		data, err = fl.Storage.Read(p, func(read uint32) {
+			if progress != nil {
+				progress(min(int64(read), total), total)
+			}
+		})
+		return err
+	})
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: p, Err: err}
+	}
+	return io.Copy(w, bytes.NewReader(data))
+}
+
+// WriteFile creates or replaces a file with the content read from r. It returns the number of bytes written.
+// The parent directory must exist. r is read completely before the transfer starts.
+func (f *FlipperZero) WriteFile(ctx context.Context, p string, r io.Reader, progress Progress) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		if err := f.writeEmpty(ctx, p); err != nil {
+			return 0, &fs.PathError{Op: "write", Path: p, Err: err}
+		}
+		if progress != nil {
+			progress(0, 0)
+		}
+		return 0, nil
+	}
+	total := int64(len(data))
+	err = f.rpc(ctx, func(fl *flipper.Flipper) error {
+		return fl.Storage.Write(p, data, func(written uint32) {
+			if progress != nil {
+				progress(int64(written), total)
+			}
+		})
+	})
+	if err != nil {
+		return 0, &fs.PathError{Op: "write", Path: p, Err: err}
+	}
+	return total, nil
+}
+
+// writeEmpty creates or truncates a file. The rpc client sends no request for empty data,
+// so the write request is sent as a raw call.
+func (f *FlipperZero) writeEmpty(ctx context.Context, p string) error {
+	return f.rpc(ctx, func(fl *flipper.Flipper) error {
+		_, err := f.rawCall(fl, fieldStorageWriteRequest, emptyWriteRequest(p))
+		return err
+	})
+}
+
+// emptyWriteRequest encodes a PB_Storage.WriteRequest without data.
+func emptyWriteRequest(p string) []byte {
+	const (
+		fieldWritePath protowire.Number = 1
+		fieldWriteFile protowire.Number = 2
+	)
+	var req []byte
+	req = protowire.AppendTag(req, fieldWritePath, protowire.BytesType)
+	req = protowire.AppendString(req, p)
+	req = protowire.AppendTag(req, fieldWriteFile, protowire.BytesType)
+	return protowire.AppendBytes(req, nil)
+}
+
+// Mkdir creates a directory. The parent directory must exist.
+func (f *FlipperZero) Mkdir(ctx context.Context, p string) error {
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		return fl.Storage.Mkdir(p)
+	})
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: err}
+	}
+	return nil
+}
+
+// Rename renames or moves a file or directory.
+func (f *FlipperZero) Rename(ctx context.Context, oldPath, newPath string) error {
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		return fl.Storage.Rename(oldPath, newPath)
+	})
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
+
+// Remove removes a file or directory. Non-empty directories are only removed if recursive is set.
+func (f *FlipperZero) Remove(ctx context.Context, p string, recursive bool) error {
+	// the flipper reports success for paths that don't exist
+	if _, err := f.Stat(ctx, p); err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			pe.Op = "remove"
+		}
+		return err
+	}
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		return fl.Storage.Delete(p, recursive)
+	})
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: p, Err: err}
+	}
+	return nil
+}
+
+// Md5Sum returns the hex encoded md5 sum of a file, computed on the flipper zero.
+func (f *FlipperZero) Md5Sum(ctx context.Context, p string) (string, error) {
+	var sum string
+	err := f.rpc(ctx, func(fl *flipper.Flipper) error {
+		var err error
+		sum, err = fl.Storage.GetMd5Sum(p)
+		return err
+	})
+	if err != nil {
+		return "", &fs.PathError{Op: "md5sum", Path: p, Err: err}
+	}
+	return sum, nil
+}
+
+// StorageInfo returns the total and free space of a storage, e.g. /ext.
+func (f *FlipperZero) StorageInfo(ctx context.Context, p string) (total, free uint64, err error) {
+	err = f.rpc(ctx, func(fl *flipper.Flipper) error {
+		var err error
+		total, free, err = fl.Storage.Info(p)
+		return err
+	})
+	if err != nil {
+		return 0, 0, &fs.PathError{Op: "info", Path: p, Err: err}
+	}
+	return total, free, nil
+}
+
+// rpc runs an rpc call once the flipper zero is connected.
+// The calls are serialized, so a long transfer doesn't make other calls time out.
+// The input events and the screen stream are not blocked meanwhile.
+// If ctx is done first, the call keeps running in the background, but its result is discarded.
+func (f *FlipperZero) rpc(ctx context.Context, fn func(fl *flipper.Flipper) error) error {
+	select {
+	case f.rpcSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	fl, conn, err := f.waitConnected(ctx)
+	if err != nil {
+		<-f.rpcSem
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-f.rpcSem }()
+		done <- fn(fl)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err == nil {
+		return nil
+	}
+
+	// go-flipper doesn't tell if the connection was lost, so check it ourselves
+	if conn.Alive() != nil || !f.isCurrent(fl) {
+		return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+	if mapped, ok := statusErrors[err.Error()]; ok {
+		return mapped
+	}
+	return err
+}
+
+// waitConnected waits until the flipper zero is connected and returns the current rpc client and connection.
+func (f *FlipperZero) waitConnected(ctx context.Context) (*flipper.Flipper, Conn, error) {
+	sub := f.Subscribe()
+	defer sub.Close()
+	for {
+		f.mu.Lock()
+		fl, conn, closing := f.flipper, f.conn, f.isClosing
+		f.mu.Unlock()
+		if closing {
+			if err := f.Err(); err != nil {
+				return nil, nil, fmt.Errorf("%w: %w", ErrClosed, err)
+			}
+			return nil, nil, ErrClosed
+		}
+		if fl != nil {
+			return fl, conn, nil
+		}
+		select {
+		case <-sub.Events():
+		case <-f.ctx.Done():
+			return nil, nil, ErrClosed
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// isCurrent returns true if fl is the rpc client of the current connection.
+func (f *FlipperZero) isCurrent(fl *flipper.Flipper) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flipper == fl
+}
@@ -0,0 +1,152 @@
+package recfz_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/jon4hz/fztea/recfz"
+)
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	f, _ := connectFake(t, recfz.WithoutScreenStream())
+	ctx := testContext(t)
+
+	if err := f.Mkdir(ctx, "/ext/test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Mkdir(ctx, "/ext/test"); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+
+	// spans multiple write requests
+	data := bytes.Repeat([]byte("0123456789"), 200)
+	var done, total int64
+	n, err := f.WriteFile(ctx, "/ext/test/data.bin", bytes.NewReader(data), func(d, t int64) { done, total = d, t })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || done != total || total != int64(len(data)) {
+		t.Fatalf("unexpected progress %d/%d after writing %d bytes", done, total, n)
+	}
+
+	entries, err := f.List(ctx, "/ext/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "data.bin" || entries[0].Size() != int64(len(data)) || entries[0].IsDir() {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.ReadFile(ctx, "/ext/test/data.bin", &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("read data differs from written data")
+	}
+
+	sum, err := f.Md5Sum(ctx, "/ext/test/data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != md5Hex(data) {
+		t.Fatalf("unexpected md5 sum %s", sum)
+	}
+
+	if err := f.Rename(ctx, "/ext/test/data.bin", "/ext/test/moved.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Stat(ctx, "/ext/test/data.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	if err := f.Remove(ctx, "/ext/test", false); !errors.Is(err, recfz.ErrDirNotEmpty) {
+		t.Fatalf("expected ErrDirNotEmpty, got %v", err)
+	}
+	if err := f.Remove(ctx, "/ext/test", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Remove(ctx, "/ext/test", true); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := f.Md5Sum(ctx, "/ext/test/moved.bin"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestWriteEmptyFile(t *testing.T) {
+	f, _ := connectFake(t)
+	ctx := testContext(t)
+
+	if _, err := f.WriteFile(ctx, "/ext/empty.txt", bytes.NewReader(nil), nil); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat(ctx, "/ext/empty.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 || fi.IsDir() {
+		t.Fatalf("unexpected file %s with %d bytes", fi.Name(), fi.Size())
+	}
+
+	// truncates an existing file
+	if _, err := f.WriteFile(ctx, "/ext/data.txt", bytes.NewReader([]byte("data")), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteFile(ctx, "/ext/data.txt", bytes.NewReader(nil), nil); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.ReadFile(ctx, "/ext/data.txt", &buf, nil); err != nil || buf.Len() != 0 {
+		t.Fatalf("expected an empty file, got %q: %v", buf.String(), err)
+	}
+	sum, err := f.Md5Sum(ctx, "/ext/data.txt")
+	if err != nil || sum != md5Hex(nil) {
+		t.Fatalf("unexpected md5 sum %s: %v", sum, err)
+	}
+
+	if _, err := f.WriteFile(ctx, "/ext/missing/empty.txt", bytes.NewReader(nil), nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := f.WriteFile(ctx, "/ext/apps", bytes.NewReader(nil), nil); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected fs.ErrInvalid, got %v", err)
+	}
+
+	// the connection is still usable
+	if _, err := f.DeviceInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KiB",
+		1536:          "1.5 KiB",
+		64 << 20:      "64.0 MiB",
+		3 << 30:       "3.0 GiB",
+		5<<40 + 1<<39: "5.5 TiB",
+	} {
+		if got := recfz.FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package recfz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const tcpScheme = "tcp://"
+
+// Transport opens raw connections to a flipper zero device.
+// The rpc session is started by recfz once the connection is open.
+type Transport interface {
+	// Dial opens a new connection to the flipper zero device.
+	Dial(ctx context.Context) (Conn, error)
+	// String returns a human readable description of the target, e.g. the serial port.
+	String() string
+}
+
+// Conn is a raw connection to a flipper zero device.
+type Conn interface {
+	Read(p []byte) (n int, err error)
+	Write(p []byte) (n int, err error)
+	Close() error
+	// Alive returns an error if the connection is lost.
+	Alive() error
+}
+
+// Detector is an optional interface implemented by transports which can check if the device is available without connecting to it.
+type Detector interface {
+	// Available returns an error if the device is not available.
+	Available() error
+}
+
+// WithTransport sets a custom transport for the flipper zero.
+// If set, the port is ignored.
+func WithTransport(t Transport) Opts {
+	return func(f *FlipperZero) {
+		f.transport = t
+	}
+}
+
+// newTransport returns the transport for the given port.
+// Ports prefixed with tcp:// are reached over tcp, everything else is treated as a serial port.
+// If the port is empty, the flipper matching the selector is autodetected on every dial.
+// Unless lazy is set, the flipper is also autodetected immediately.
+func newTransport(port string, sel Selector, logger *log.Logger, lazy bool) (Transport, error) {
+	if strings.HasPrefix(port, tcpScheme) {
+		addr := strings.TrimPrefix(port, tcpScheme)
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid tcp address %q: %w", addr, err)
+		}
+		return &tcpTransport{addr: addr}, nil
+	}
+	if port != "" && !sel.IsZero() {
+		return nil, errors.New("a static port can't be combined with a device selector")
+	}
+	return newSerialTransport(port, sel, logger, lazy)
+}
+
+// serialTransport connects to a flipper zero over a local serial port.
+type serialTransport struct {
+	mu         sync.Mutex
+	port       string
+	staticPort bool
+	selector   Selector
+	logger     *log.Logger
+}
+
+// newSerialTransport returns a new serial transport.
+// If the port is empty, the flipper is autodetected on every dial and, unless lazy is set, immediately.
+func newSerialTransport(port string, sel Selector, logger *log.Logger, lazy bool) (*serialTransport, error) {
+	t := &serialTransport{
+		port:       port,
+		staticPort: port != "",
+		selector:   sel,
+		logger:     logger,
+	}
+	if !t.staticPort && !lazy {
+		p, err := t.autodetect()
+		if err != nil {
+			return nil, fmt.Errorf("could not autodetect flipper: %w", err)
+		}
+		t.port = p
+	}
+	return t, nil
+}
+
+// Dial opens the serial port. If the port is not static, the flipper is autodetected first.
+func (t *serialTransport) Dial(_ context.Context) (Conn, error) {
+	t.mu.Lock()
+	port := t.port
+	t.mu.Unlock()
+	if !t.staticPort {
+		var err error
+		port, err = t.autodetect()
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.port = port
+		t.mu.Unlock()
+	}
+	ser, err := serial.Open(port, &serial.Mode{})
+	if err != nil {
+		return nil, err
+	}
+	return &serialConn{Port: ser}, nil
+}
+
+// String returns the serial port.
+func (t *serialTransport) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.port == "" {
+		return "auto-detect"
+	}
+	return t.port
+}
+
+// Available checks if the serial port exists or, if the port is not static, if a flipper can be detected.
+func (t *serialTransport) Available() error {
+	if t.staticPort {
+		_, err := os.Stat(t.port)
+		return err
+	}
+	_, err := t.autodetect()
+	return err
+}
+
+// autodetect tries to automatically detect the flipper zero device.
+// If multiple devices match the selector, the first one is used.
+func (t *serialTransport) autodetect() (string, error) {
+	devices, err := ListDevices()
+	if err != nil {
+		return "", err
+	}
+
+	for _, d := range devices {
+		if t.selector.Matches(d) {
+			t.logger.Printf("found flipper %s on %s", d.Name, d.Port)
+			return d.Port, nil
+		}
+	}
+	if !t.selector.IsZero() {
+		return "", errors.New("no matching flipper found")
+	}
+	return "", errors.New("no flipper found")
+}
+
+// serialConn is a connection over a serial port.
+type serialConn struct {
+	serial.Port
+}
+
+// Alive checks the connection by writing an empty message.
+func (c *serialConn) Alive() error {
+	_, err := c.Write(nil)
+	return err
+}
+
+// tcpTransport connects to a flipper zero over tcp, e.g. a serial port exposed by ser2net.
+type tcpTransport struct {
+	addr string
+}
+
+// Dial opens a tcp connection.
+func (t *tcpTransport) Dial(ctx context.Context) (Conn, error) {
+	d := net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 5 * time.Second,
+	}
+	conn, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpConn{Conn: conn}, nil
+}
+
+// String returns the tcp address including the scheme.
+func (t *tcpTransport) String() string {
+	return tcpScheme + t.addr
+}
+
+// tcpConn is a connection over tcp.
+// It remembers the first error that occurred while reading or writing.
+type tcpConn struct {
+	net.Conn
+	mu  sync.Mutex
+	err error
+}
+
+// Read reads from the tcp connection.
+func (c *tcpConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.setErr(err)
+	return n, err
+}
+
+// Write writes to the tcp connection.
+func (c *tcpConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.setErr(err)
+	return n, err
+}
+
+// Alive returns the first error that occurred on the connection.
+func (c *tcpConn) Alive() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *tcpConn) setErr(err error) {
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
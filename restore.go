@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var restoreFlags struct {
+	dryRun   bool
+	noVerify bool
+	filter   filterFlags
+}
+
+var restoreCmd = &coral.Command{
+	Use:          "restore archive",
+	Short:        "Restore a backup created by fztea backup onto the flipper",
+	Example:      "  fztea restore flipper-2026-10-17.tar.gz --include /ext/subghz --dry-run",
+	Args:         coral.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         restore,
+}
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreFlags.dryRun, "dry-run", "n", false, "only show what would be restored")
+	restoreCmd.Flags().BoolVar(&restoreFlags.noVerify, "no-verify", false, "don't compare the md5 sums of the restored files")
+	restoreFlags.filter.register(restoreCmd)
+	addTransferFlags(restoreCmd)
+}
+
+func restore(cmd *coral.Command, args []string) error {
+	if err := restoreFlags.filter.validate(); err != nil {
+		return err
+	}
+	archive := args[0]
+	m, err := readManifest(archive)
+	if err != nil {
+		return err
+	}
+	sums := make(map[string]manifestFile, len(m.Files))
+	for _, f := range m.Files {
+		sums[f.Path] = f
+	}
+
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	t, err := newTransfer(cmd.Context(), fz)
+	if err != nil {
+		return err
+	}
+	r := &restorer{transfer: t, sums: sums, dirs: make(map[string]bool)}
+	return r.restoreArchive(archive)
+}
+
+// restorer writes the files of a backup archive to the flipper.
+type restorer struct {
+	*transfer
+	sums map[string]manifestFile
+	// dirs are the directories which were created or exist on the flipper.
+	dirs map[string]bool
+}
+
+// restoreArchive writes the included files of a backup archive to the flipper.
+func (r *restorer) restoreArchive(archive string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", archive, err)
+		}
+		if hdr.Name == manifestName {
+			continue
+		}
+		p, err := devicePath(hdr.Name)
+		if err != nil {
+			r.fail(err)
+			continue
+		}
+		if restoreFlags.filter.excluded(p) || !restoreFlags.filter.included(p) {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			r.restoreDir(p)
+		case tar.TypeReg:
+			r.restoreFile(p, tr)
+		}
+	}
+	return r.err()
+}
+
+// restoreDir creates a directory of the archive on the flipper.
+// The directories leading to the included ones are created with the files in them.
+func (r *restorer) restoreDir(p string) {
+	if restoreFlags.dryRun {
+		return
+	}
+	if err := r.mkdirAll(p); err != nil {
+		r.fail(err)
+	}
+}
+
+// mkdirAll creates a directory and its parents on the flipper, unless it was done before.
+func (r *restorer) mkdirAll(p string) error {
+	if r.dirs[p] {
+		return nil
+	}
+	if err := mkdirAllRemote(r.ctx, r.fz, p); err != nil {
+		return err
+	}
+	r.dirs[p] = true
+	return nil
+}
+
+// restoreFile writes a file of the archive to the flipper.
+// The content is checked against the manifest before and, unless disabled, after writing.
+func (r *restorer) restoreFile(p string, content io.Reader) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	want, ok := r.sums[p]
+	if !ok {
+		r.fail(fmt.Errorf("%s: not in the manifest", p))
+		return
+	}
+	sum := md5.Sum(data) //nolint:gosec
+	if hex.EncodeToString(sum[:]) != want.MD5 {
+		r.fail(fmt.Errorf("%s: corrupted archive, the md5 sum doesn't match the manifest", p))
+		return
+	}
+
+	if _, err := r.fz.Stat(r.ctx, p); err == nil && r.skip(p) {
+		return
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		r.fail(err)
+		return
+	}
+	if restoreFlags.dryRun {
+		r.done++
+		if !transferFlags.quiet {
+			fmt.Printf("would restore %s (%s)\n", p, recfz.FormatSize(int64(len(data))))
+		}
+		return
+	}
+
+	if err := r.mkdirAll(path.Dir(p)); err != nil {
+		r.fail(err)
+		return
+	}
+	bar := newProgressBar(p)
+	err = r.retry(func() error {
+		_, err := r.fz.WriteFile(r.ctx, p, bytes.NewReader(data), bar.update)
+		return err
+	})
+	bar.clear()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if !restoreFlags.noVerify {
+		got, err := r.fz.Md5Sum(r.ctx, p)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		if !strings.EqualFold(got, want.MD5) {
+			r.fail(fmt.Errorf("%s: verification failed, the md5 sum on the flipper doesn't match the manifest", p))
+			return
+		}
+	}
+	r.copied(archivePath(p), p, int64(len(data)))
+}
+
+// readManifest reads the manifest of a backup archive.
+func readManifest(archive string) (*manifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", archive, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s has no manifest, it wasn't created by fztea backup", archive)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", archive, err)
+		}
+		if hdr.Name != manifestName {
+			continue
+		}
+		var m manifest
+		if err := json.NewDecoder(tr).Decode(&m); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if m.Version != manifestVersion {
+			return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+		}
+		return &m, nil
+	}
+}
+
+// devicePath returns the path on the flipper of an archive entry.
+// Only entries below ext/ and int/ are accepted.
+func devicePath(name string) (string, error) {
+	p := path.Clean("/" + name)
+	if p != "/ext" && p != "/int" && !strings.HasPrefix(p, "/ext/") && !strings.HasPrefix(p, "/int/") {
+		return "", fmt.Errorf("%s: not a path on the flipper", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jon4hz/fztea/fakefz"
+	"github.com/jon4hz/fztea/internal/race"
+	"github.com/jon4hz/fztea/recfz"
+)
+
+// writeArchive writes a backup archive with the given directories and files.
+func writeArchive(t *testing.T, dirs []string, files map[string]string) (string, map[string]manifestFile) {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, d := range dirs {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: archivePath(d) + "/", Mode: 0o755}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sums := make(map[string]manifestFile, len(files))
+	for p, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: archivePath(p), Size: int64(len(content)), Mode: 0o644}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatal(err)
+		}
+		sum := md5.Sum([]byte(content)) //nolint:gosec
+		sums[p] = manifestFile{Path: p, Size: int64(len(content)), MD5: hex.EncodeToString(sum[:])}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive, sums
+}
+
+func TestRestoreIncludesOnlyMatchingDirs(t *testing.T) {
+	race.SkipGoFlipper(t)
+	archive, sums := writeArchive(t,
+		[]string{"/ext/lab", "/ext/lab/keep", "/ext/lab/keep/empty", "/ext/lab/other", "/ext/other"},
+		map[string]string{"/ext/lab/keep/gate.sub": "Filetype: Flipper SubGhz Key File\n"},
+	)
+	d := fakefz.NewDevice()
+	defer d.Close()
+	fz, err := recfz.NewFlipperZero(recfz.WithTransport(d.Transport()), recfz.WithoutScreenStream(), recfz.WithLogger(log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fz.Close()
+	if err := fz.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restoreFlags.filter.include = []string{"/ext/lab/keep"}
+	transferFlags.quiet = true
+	defer func() {
+		restoreFlags.filter.include = nil
+		transferFlags.quiet = false
+	}()
+	r := &restorer{transfer: &transfer{ctx: ctx, fz: fz}, sums: sums, dirs: make(map[string]bool)}
+	if err := r.restoreArchive(archive); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"/ext/lab/keep/gate.sub", "/ext/lab/keep/empty"} {
+		if _, err := fz.Stat(ctx, p); err != nil {
+			t.Errorf("expected %s to be restored: %v", p, err)
+		}
+	}
+	for _, p := range []string{"/ext/lab/other", "/ext/other"} {
+		if _, err := fz.Stat(ctx, p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %s not to be restored, got %v", p, err)
+		}
+	}
+}
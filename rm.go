@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var rmFlags struct {
+	recursive bool
+	force     bool
+}
+
+var rmCmd = &coral.Command{
+	Use:          "rm path...",
+	Short:        "Remove files and directories on the flipper",
+	Args:         coral.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         rm,
+}
+
+func init() {
+	rmCmd.Flags().BoolVarP(&rmFlags.recursive, "recursive", "r", false, "remove directories and their content")
+	rmCmd.Flags().BoolVarP(&rmFlags.force, "force", "f", false, "ignore files that don't exist")
+}
+
+func rm(cmd *coral.Command, args []string) error {
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	ctx := cmd.Context()
+
+	var failed bool
+	for _, arg := range args {
+		paths, err := expandRemote(ctx, fz, arg)
+		if err != nil {
+			if !rmFlags.force {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			continue
+		}
+		for _, p := range paths {
+			if err := remove(cmd, fz, p); err != nil {
+				if rmFlags.force && errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return fmt.Errorf("could not remove all files")
+	}
+	return nil
+}
+
+// remove removes a single file or directory. Like rm, directories are only removed with --recursive.
+func remove(cmd *coral.Command, fz *recfz.FlipperZero, p string) error {
+	fi, err := fz.Stat(cmd.Context(), p)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() && !rmFlags.recursive {
+		return fmt.Errorf("remove %s: is a directory, use --recursive", p)
+	}
+	return fz.Remove(cmd.Context(), p, rmFlags.recursive)
+}
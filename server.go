@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,6 +27,10 @@ import (
 var serverFlags struct {
 	listen         string
 	authorizedKeys string
+	maxViewers     int
+	sftp           bool
+	webdav         string
+	webdavAuth     string
 }
 
 var serverCmd = &coral.Command{
@@ -32,6 +42,10 @@ var serverCmd = &coral.Command{
 func init() {
 	serverCmd.Flags().StringVarP(&serverFlags.listen, "listen", "l", "127.0.0.1:2222", "address to listen on")
 	serverCmd.Flags().StringVarP(&serverFlags.authorizedKeys, "authorized-keys", "k", "", "authorized_keys file for public key authentication")
+	serverCmd.Flags().IntVar(&serverFlags.maxViewers, "max-viewers", 1, "maximum number of concurrent sessions, only one of them controls the flipper")
+	serverCmd.Flags().BoolVar(&serverFlags.sftp, "sftp", false, "serve the storage of the flipper over sftp")
+	serverCmd.Flags().StringVar(&serverFlags.webdav, "webdav", "", "address to serve the storage of the flipper over webdav on, e.g. 127.0.0.1:8080. Other addresses require --webdav-auth")
+	serverCmd.Flags().StringVar(&serverFlags.webdavAuth, "webdav-auth", "", "user:password required to access the webdav share")
 }
 
 func server(cmd *coral.Command, _ []string) {
@@ -41,37 +55,78 @@ func server(cmd *coral.Command, _ []string) {
 		log.Fatalf("failed to parse screenshot resolution: %s", err)
 	}
 
-	screenUpdates := make(chan flipperui.ScreenMsg)
+	// check the renderer once, it's detected for every session
+	if _, err := flipperui.NewRenderer(rootFlags.renderer, os.Getenv); err != nil {
+		log.Fatal(err)
+	}
+	themes, err := themeOpts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	screens := flipperui.NewBroadcaster()
 	fz, err := recfz.NewFlipperZero(
-		recfz.WithPort(rootFlags.port),
-		recfz.WithStreamScreenCallback(flipperui.UpdateScreen(screenUpdates)),
-		recfz.WithContext(cmd.Context()),
+		append(connOpts(),
+			recfz.WithStreamScreenCallback(screens.Callback()),
+			recfz.WithContext(cmd.Context()),
+		)...,
 	)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer fz.Close()
+	go logStates(fz.Subscribe())
 	if err := fz.Connect(); err != nil {
 		log.Fatal(err)
 	}
 
-	cl := newConnLimiter(1)
+	cl := newConnLimiter(serverFlags.maxViewers)
+	ctrl := newController()
 
 	sshOpts := []ssh.Option{
 		wish.WithAddress(serverFlags.listen),
 		wish.WithHostKeyPath(".ssh/flipperzero_tea_ed25519"),
 		wish.WithMiddleware(
 			bm.Middleware(func(s ssh.Session) (tea.Model, []tea.ProgramOption) {
-				_, _, active := s.Pty()
+				pty, _, active := s.Pty()
 				if !active {
 					wish.Fatalln(s, "no active terminal, skipping")
 					return nil, nil
 				}
+				// every session gets its own subscription, which is closed when the session ends
+				sub := screens.Subscribe()
+				states := fz.Subscribe()
+				v := ctrl.Join(fmt.Sprintf("%s@%s", s.User(), remoteHost(s)))
+				go func() {
+					<-s.Context().Done()
+					sub.Close()
+					states.Close()
+					ctrl.Leave(v)
+				}()
+				renderers, err := flipperui.NewRenderers(rootFlags.renderer, sessionEnv(s, pty))
+				if err != nil {
+					wish.Fatalln(s, err)
+					return nil, nil
+				}
+				// every session cycles its own theme
+				opts := append(slices.Clone(themes),
+					flipperui.WithScreenshotResolution(screenshotResolution.width, screenshotResolution.height),
+					flipperui.WithInputAllowed(func() bool { return ctrl.IsDriver(v) }),
+					flipperui.WithStateUpdates(states.Events()),
+					flipperui.WithRenderers(renderers...),
+					flipperui.WithScale(rootFlags.scale),
+				)
+				if rootFlags.statusBar {
+					opts = append(opts, flipperui.WithStatusBar())
+				}
+				if rootFlags.skin {
+					opts = append(opts, flipperui.WithSkin())
+				}
 				m := model{
-					flipper: flipperui.New(fz, screenUpdates,
-						flipperui.WithScreenshotResolution(screenshotResolution.width, screenshotResolution.height),
-						flipperui.WithFgColor(rootFlags.fgColor),
-						flipperui.WithBgColor(rootFlags.bgColor)),
+					flipper: flipperui.New(fz, sub.Updates(), opts...),
+					ctrl:    ctrl,
+					viewer:  v,
+					info:    flipperui.NewInfoPanel(fz),
 				}
 				return m, []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
 			}),
@@ -80,6 +135,9 @@ func server(cmd *coral.Command, _ []string) {
 		),
 	}
 
+	if serverFlags.sftp {
+		sshOpts = append(sshOpts, wish.WithSubsystem("sftp", sftpHandler(fz)))
+	}
 	if serverFlags.authorizedKeys != "" {
 		sshOpts = append(sshOpts, wish.WithAuthorizedKeys(serverFlags.authorizedKeys))
 	}
@@ -91,6 +149,14 @@ func server(cmd *coral.Command, _ []string) {
 		log.Fatalln(err)
 	}
 
+	var dav *http.Server
+	if serverFlags.webdav != "" {
+		dav, err = newWebDAVServer(serverFlags.webdav, serverFlags.webdavAuth, fz)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	log.Printf("Starting SSH server on %s", serverFlags.listen)
@@ -99,12 +165,65 @@ func server(cmd *coral.Command, _ []string) {
 			log.Fatalln(err)
 		}
 	}()
+	if dav != nil {
+		log.Printf("Starting WebDAV server on %s", serverFlags.webdav)
+		go func() {
+			if err := dav.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalln(err)
+			}
+		}()
+	}
 
-	<-done
+	var exitErr error
+	select {
+	case <-done:
+	case <-fz.Done():
+		exitErr = fz.Err()
+	}
 	log.Println("Stopping SSH server")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer func() { cancel() }()
 	if err := s.Shutdown(ctx); err != nil {
 		log.Fatalln(err)
 	}
+	if dav != nil {
+		if err := dav.Shutdown(ctx); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if exitErr != nil {
+		log.Fatalln(exitErr)
+	}
+}
+
+// logStates logs every connection state change of the flipper.
+func logStates(sub *recfz.Subscription) {
+	for e := range sub.Events() {
+		log.Printf("flipper %s", e)
+	}
+}
+
+// sessionEnv returns a getenv function for the environment of an ssh session, e.g. to detect the renderer.
+// Most clients only pass a few variables like LC_TERMINAL, TERM is taken from the pty.
+func sessionEnv(s ssh.Session, pty ssh.Pty) func(string) string {
+	return func(key string) string {
+		if key == "TERM" {
+			return pty.Term
+		}
+		for _, kv := range s.Environ() {
+			if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+				return v
+			}
+		}
+		return ""
+	}
+}
+
+// remoteHost returns the host of the remote address of a session.
+func remoteHost(s ssh.Session) string {
+	host, _, err := net.SplitHostPort(s.RemoteAddr().String())
+	if err != nil {
+		return s.RemoteAddr().String()
+	}
+	return host
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/pkg/sftp"
+)
+
+// sftpHandler serves the storage of the flipper over sftp.
+// The storage calls are serialized by recfz, so transfers don't interfere with the screen stream.
+func sftpHandler(fz *recfz.FlipperZero) ssh.SubsystemHandler {
+	return func(s ssh.Session) {
+		log.Printf("sftp session started by %s@%s", s.User(), remoteHost(s))
+		h := &sftpFS{fz: fz}
+		server := sftp.NewRequestServer(s, sftp.Handlers{
+			FileGet:  h,
+			FilePut:  h,
+			FileCmd:  h,
+			FileList: h,
+		})
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+			log.Printf("sftp session of %s@%s failed: %s", s.User(), remoteHost(s), err)
+		}
+		server.Close()
+	}
+}
+
+// sftpFS implements the sftp handlers on top of the storage of the flipper.
+// The root directory is virtual and contains the storages ext and int.
+type sftpFS struct {
+	fz *recfz.FlipperZero
+}
+
+// Fileread reads the whole file, the flipper doesn't support reading at an offset.
+func (s *sftpFS) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	var buf bytes.Buffer
+	if _, err := s.fz.ReadFile(r.Context(), r.Filepath, &buf, nil); err != nil {
+		return nil, sftpError(err)
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// Filewrite buffers the file and writes it to the flipper when it's closed.
+func (s *sftpFS) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	if isVirtualDir(r.Filepath) {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	w := &sftpWriter{fs: s, req: r}
+	if flags := r.Pflags(); !flags.Trunc {
+		// keep the existing content, e.g. when appending or resuming
+		var buf bytes.Buffer
+		_, err := s.fz.ReadFile(r.Context(), r.Filepath, &buf, nil)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, sftpError(err)
+		}
+		w.data = buf.Bytes()
+	}
+	return w, nil
+}
+
+// Filecmd handles the commands changing the storage.
+func (s *sftpFS) Filecmd(r *sftp.Request) error {
+	ctx := r.Context()
+	switch r.Method {
+	case "Setstat":
+		// the flipper has no permissions or modification times
+		return nil
+	case "Rename":
+		if isVirtualDir(r.Filepath) || isVirtualDir(r.Target) {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		return sftpError(s.fz.Rename(ctx, r.Filepath, r.Target))
+	case "Remove", "Rmdir":
+		if isVirtualDir(r.Filepath) {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		fi, err := s.fz.Stat(ctx, r.Filepath)
+		if err != nil {
+			return sftpError(err)
+		}
+		if fi.IsDir() != (r.Method == "Rmdir") {
+			return sftp.ErrSSHFxFailure
+		}
+		return sftpError(s.fz.Remove(ctx, r.Filepath, false))
+	case "Mkdir":
+		if isVirtualDir(r.Filepath) {
+			return sftpError(&fs.PathError{Op: "mkdir", Path: r.Filepath, Err: fs.ErrExist})
+		}
+		return sftpError(s.fz.Mkdir(ctx, r.Filepath))
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// Filelist handles listing directories and stating files.
+func (s *sftpFS) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	ctx := r.Context()
+	switch r.Method {
+	case "List":
+		if r.Filepath == "/" {
+			return sftpLister{virtualDir("ext"), virtualDir("int")}, nil
+		}
+		files, err := s.fz.List(ctx, r.Filepath)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		return sftpLister(files), nil
+	case "Stat":
+		if isVirtualDir(r.Filepath) {
+			return sftpLister{virtualDir(path.Base(r.Filepath))}, nil
+		}
+		fi, err := s.fz.Stat(ctx, r.Filepath)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		return sftpLister{fi}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// sftpWriter collects the written data in memory.
+type sftpWriter struct {
+	fs   *sftpFS
+	req  *sftp.Request
+	mu   sync.Mutex
+	data []byte
+}
+
+// WriteAt writes p at the offset off, growing the buffer if needed.
+func (w *sftpWriter) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	return copy(w.data[off:], p), nil
+}
+
+// Close writes the file to the flipper.
+func (w *sftpWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.fs.fz.WriteFile(w.req.Context(), w.req.Filepath, bytes.NewReader(w.data), nil)
+	return sftpError(err)
+}
+
+// sftpLister implements sftp.ListerAt for a slice of files.
+type sftpLister []fs.FileInfo
+
+// ListAt copies the files starting at offset into ls.
+func (l sftpLister) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// virtualDir is a directory which doesn't exist on the flipper, e.g. the root directory.
+type virtualDir string
+
+func (d virtualDir) Name() string       { return string(d) }
+func (d virtualDir) Size() int64        { return 0 }
+func (d virtualDir) Mode() fs.FileMode  { return fs.ModeDir | 0o755 }
+func (d virtualDir) ModTime() time.Time { return time.Time{} }
+func (d virtualDir) IsDir() bool        { return true }
+func (d virtualDir) Sys() any           { return nil }
+
+// isVirtualDir returns true for the root directory and the storages, which can't be changed.
+func isVirtualDir(p string) bool {
+	p = path.Clean("/" + p)
+	return p == "/" || p == "/ext" || p == "/int"
+}
+
+// sftpError maps the errors of the storage to sftp status codes.
+// Errors of missing files are already mapped by the sftp server.
+func sftpError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrPermission):
+		return sftp.ErrSSHFxPermissionDenied
+	case errors.Is(err, recfz.ErrConnectionLost), errors.Is(err, recfz.ErrClosed):
+		return sftp.ErrSSHFxConnectionLost
+	}
+	return err
+}
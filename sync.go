@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/muesli/coral"
+)
+
+var syncFlags struct {
+	pull   bool
+	delete bool
+	dryRun bool
+}
+
+var syncCmd = &coral.Command{
+	Use:   "sync local remote",
+	Short: "Synchronize a local directory with a directory on the flipper",
+	Long: "Synchronize a local directory with a directory on the flipper.\n\n" +
+		"Files are compared by size and md5 sum, only new and changed files are copied.\n" +
+		"By default the local directory is copied to the flipper, use --pull for the other direction.",
+	Example: "  fztea sync ./library/infrared /ext/infrared --delete\n" +
+		"  fztea sync ./library/subghz /ext/subghz --pull --dry-run",
+	Args:         coral.ExactArgs(2),
+	SilenceUsage: true,
+	RunE:         syncDirs,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncFlags.pull, "pull", false, "copy from the flipper to the local directory")
+	syncCmd.Flags().BoolVar(&syncFlags.delete, "delete", false, "remove files that don't exist in the source")
+	syncCmd.Flags().BoolVarP(&syncFlags.dryRun, "dry-run", "n", false, "only show the differences")
+	addQuietFlag(syncCmd)
+}
+
+// syncEntry is a file or directory of a synchronized tree.
+type syncEntry struct {
+	dir  bool
+	size int64
+}
+
+// syncTree maps the slash separated paths relative to the root of the tree to their entries.
+type syncTree map[string]syncEntry
+
+// syncer compares and copies the files of a local and a remote directory.
+// All operations use the same connection, so the rpc session is only started once.
+type syncer struct {
+	*transfer
+	local, remote string
+	added         int
+	updated       int
+	deleted       int
+	unchanged     int
+}
+
+func syncDirs(cmd *coral.Command, args []string) error {
+	local := filepath.Clean(args[0])
+	remote := path.Clean(args[1])
+
+	fz, err := connect(cmd, recfz.WithoutScreenStream())
+	if err != nil {
+		return err
+	}
+	defer fz.Close()
+	t, err := newTransfer(cmd.Context(), fz)
+	if err != nil {
+		return err
+	}
+	s := &syncer{transfer: t, local: local, remote: remote}
+
+	localTree, err := s.localTree()
+	if err != nil {
+		return err
+	}
+	remoteTree, err := s.remoteTree()
+	if err != nil {
+		return err
+	}
+	if syncFlags.pull {
+		s.run(remoteTree, localTree, s.download, s.removeLocal, s.mkdirLocal)
+	} else {
+		s.run(localTree, remoteTree, s.upload, s.removeRemote, s.mkdirRemote)
+	}
+
+	if !transferFlags.quiet {
+		fmt.Printf("%d added, %d updated, %d deleted, %d unchanged\n", s.added, s.updated, s.deleted, s.unchanged)
+	}
+	return t.err()
+}
+
+// run makes dst equal to src. Paths passed to the functions are relative to the roots.
+func (s *syncer) run(src, dst syncTree, copyFile func(rel string) error, remove func(rel string) error, mkdir func(rel string) error) {
+	if _, ok := dst[""]; !ok && !syncFlags.dryRun {
+		if err := mkdir(""); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+
+	for _, rel := range sortedPaths(src) {
+		e := src[rel]
+		if rel == "" {
+			continue
+		}
+		d, exists := dst[rel]
+		switch {
+		case e.dir && exists && d.dir:
+			continue
+		case e.dir:
+			if exists {
+				s.fail(fmt.Errorf("%s: is a file, but a directory in the source", rel))
+				continue
+			}
+			if !syncFlags.dryRun {
+				if err := mkdir(rel); err != nil {
+					s.fail(err)
+				}
+			}
+			continue
+		case exists && d.dir:
+			s.fail(fmt.Errorf("%s: is a directory, but a file in the source", rel))
+			continue
+		case exists:
+			same, err := s.equal(rel, e, d)
+			if err != nil {
+				s.fail(err)
+				continue
+			}
+			if same {
+				s.unchanged++
+				continue
+			}
+		}
+
+		marker := "+"
+		if exists {
+			marker = "~"
+		}
+		if !syncFlags.dryRun {
+			if err := copyFile(rel); err != nil {
+				s.fail(err)
+				continue
+			}
+		}
+		s.report(marker, rel)
+		if exists {
+			s.updated++
+		} else {
+			s.added++
+		}
+		s.done++
+	}
+
+	if !syncFlags.delete {
+		return
+	}
+	var removed []string
+	for _, rel := range sortedPaths(dst) {
+		if _, ok := src[rel]; ok || isBelow(rel, removed) {
+			continue
+		}
+		removed = append(removed, rel)
+		if !syncFlags.dryRun {
+			if err := remove(rel); err != nil {
+				s.fail(err)
+				continue
+			}
+		}
+		s.report("-", rel)
+		s.deleted++
+		s.done++
+	}
+}
+
+// report prints a difference, e.g. "+ tv.ir".
+func (s *syncer) report(marker, rel string) {
+	if !transferFlags.quiet {
+		fmt.Println(marker, rel)
+	}
+}
+
+// equal compares a local and a remote file. The md5 sums are only compared if the sizes match.
+func (s *syncer) equal(rel string, a, b syncEntry) (bool, error) {
+	if a.size != b.size {
+		return false, nil
+	}
+	localSum, err := md5File(s.localPath(rel))
+	if err != nil {
+		return false, err
+	}
+	var remoteSum string
+	err = s.retry(func() error {
+		remoteSum, err = s.fz.Md5Sum(s.ctx, s.remotePath(rel))
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(localSum, remoteSum), nil
+}
+
+func (s *syncer) localPath(rel string) string  { return filepath.Join(s.local, filepath.FromSlash(rel)) }
+func (s *syncer) remotePath(rel string) string { return path.Join(s.remote, rel) }
+
+func (s *syncer) upload(rel string) error {
+	_, err := s.transfer.upload(s.localPath(rel), s.remotePath(rel))
+	return err
+}
+
+func (s *syncer) download(rel string) error {
+	_, err := s.transfer.download(s.remotePath(rel), s.localPath(rel))
+	return err
+}
+
+func (s *syncer) removeLocal(rel string) error { return os.RemoveAll(s.localPath(rel)) }
+
+func (s *syncer) removeRemote(rel string) error {
+	return s.retry(func() error { return s.fz.Remove(s.ctx, s.remotePath(rel), true) })
+}
+
+func (s *syncer) mkdirLocal(rel string) error { return os.MkdirAll(s.localPath(rel), 0o755) }
+
+func (s *syncer) mkdirRemote(rel string) error {
+	return s.retry(func() error { return mkdirAllRemote(s.ctx, s.fz, s.remotePath(rel)) })
+}
+
+// localTree walks the local directory. A missing directory is an empty tree.
+func (s *syncer) localTree() (syncTree, error) {
+	tree := make(syncTree)
+	err := filepath.WalkDir(s.local, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == s.local && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.local, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = ""
+			if !d.IsDir() {
+				return fmt.Errorf("%s: not a directory", s.local)
+			}
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		tree[filepath.ToSlash(rel)] = syncEntry{dir: d.IsDir(), size: fi.Size()}
+		return nil
+	})
+	return tree, err
+}
+
+// remoteTree walks the remote directory. A missing directory is an empty tree.
+func (s *syncer) remoteTree() (syncTree, error) {
+	tree := make(syncTree)
+	fi, err := s.fz.Stat(s.ctx, s.remote)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tree, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s: not a directory", s.remote)
+	}
+	tree[""] = syncEntry{dir: true}
+	return tree, s.walkRemote(tree, "")
+}
+
+func (s *syncer) walkRemote(tree syncTree, rel string) error {
+	var files []fs.FileInfo
+	err := s.retry(func() error {
+		var err error
+		files, err = s.fz.List(s.ctx, s.remotePath(rel))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		child := path.Join(rel, f.Name())
+		tree[child] = syncEntry{dir: f.IsDir(), size: f.Size()}
+		if f.IsDir() {
+			if err := s.walkRemote(tree, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedPaths returns the paths of a tree, parents before their children.
+func sortedPaths(tree syncTree) []string {
+	paths := make([]string, 0, len(tree))
+	for p := range tree {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isBelow returns true if p is inside one of the directories.
+func isBelow(p string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// md5File returns the hex encoded md5 sum of a local file.
+func md5File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New() //nolint:gosec
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/jon4hz/fztea/recfz"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/coral"
+)
+
+const (
+	// transferRetries is the number of times a transfer is retried if the connection was lost.
+	transferRetries = 3
+	// progressInterval is the minimum time between two updates of a progress bar.
+	progressInterval = 100 * time.Millisecond
+)
+
+// if-exists policies of the transfer commands.
+const (
+	ifExistsOverwrite = "overwrite"
+	ifExistsSkip      = "skip"
+	ifExistsFail      = "fail"
+)
+
+var transferFlags = struct {
+	ifExists string
+	quiet    bool
+}{
+	ifExists: ifExistsOverwrite,
+}
+
+// addTransferFlags adds the flags of the commands copying files.
+func addTransferFlags(cmd *coral.Command) {
+	cmd.Flags().StringVar(&transferFlags.ifExists, "if-exists", ifExistsOverwrite, "what to do with existing files: overwrite, skip or fail")
+	addQuietFlag(cmd)
+}
+
+// addQuietFlag adds the flag to silence the output of the commands copying files.
+func addQuietFlag(cmd *coral.Command) {
+	cmd.Flags().BoolVarP(&transferFlags.quiet, "quiet", "q", false, "don't print the copied files and the progress")
+}
+
+// transfer copies files and keeps track of the failures.
+// Failed files are reported, but don't stop the other files from being copied.
+type transfer struct {
+	ctx    context.Context
+	fz     *recfz.FlipperZero
+	failed int
+	done   int
+}
+
+// newTransfer validates the transfer flags and returns a new transfer.
+func newTransfer(ctx context.Context, fz *recfz.FlipperZero) (*transfer, error) {
+	switch transferFlags.ifExists {
+	case ifExistsOverwrite, ifExistsSkip, ifExistsFail:
+	default:
+		return nil, fmt.Errorf("invalid --if-exists %q, must be overwrite, skip or fail", transferFlags.ifExists)
+	}
+	return &transfer{ctx: ctx, fz: fz}, nil
+}
+
+// fail reports a failed file.
+func (t *transfer) fail(err error) {
+	t.failed++
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// copied reports a copied file.
+func (t *transfer) copied(src, dst string, size int64) {
+	t.done++
+	if !transferFlags.quiet {
+		fmt.Printf("%s -> %s (%s)\n", src, dst, recfz.FormatSize(size))
+	}
+}
+
+// skip returns true if an existing file must not be overwritten. It reports the file if so.
+func (t *transfer) skip(dst string) bool {
+	switch transferFlags.ifExists {
+	case ifExistsSkip:
+		if !transferFlags.quiet {
+			fmt.Printf("skipped %s, it already exists\n", dst)
+		}
+		return true
+	case ifExistsFail:
+		t.fail(&fs.PathError{Op: "copy", Path: dst, Err: fs.ErrExist})
+		return true
+	}
+	return false
+}
+
+// retry runs fn again if it failed because the connection was lost.
+// The storage api waits for the flipper to be reconnected.
+func (t *transfer) retry(fn func() error) error {
+	var err error
+	for i := 0; i < transferRetries; i++ {
+		if err = fn(); !errors.Is(err, recfz.ErrConnectionLost) {
+			return err
+		}
+	}
+	return err
+}
+
+// err returns an error if any file failed.
+func (t *transfer) err() error {
+	if t.failed > 0 {
+		return fmt.Errorf("%d of %d files failed", t.failed, t.failed+t.done)
+	}
+	return nil
+}
+
+// progressBar renders the progress of a single file on stderr, if stderr is a terminal.
+type progressBar struct {
+	label   string
+	bar     progress.Model
+	enabled bool
+	last    time.Time
+}
+
+func newProgressBar(label string) *progressBar {
+	return &progressBar{
+		label:   label,
+		bar:     progress.New(progress.WithDefaultGradient(), progress.WithWidth(30)),
+		enabled: !transferFlags.quiet && isatty.IsTerminal(os.Stderr.Fd()),
+	}
+}
+
+// update renders the progress. It's compatible to recfz.Progress.
+func (p *progressBar) update(done, total int64) {
+	if !p.enabled || total == 0 || time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+	fmt.Fprintf(os.Stderr, "\r\033[K%s %s %s/%s", p.label, p.bar.ViewAs(float64(done)/float64(total)), recfz.FormatSize(done), recfz.FormatSize(total))
+}
+
+// clear removes the progress bar.
+func (p *progressBar) clear() {
+	if p.enabled && !p.last.IsZero() {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+// expandRemote expands the glob patterns in a path on the flipper.
+// Paths without patterns are returned as they are, even if they don't exist.
+func expandRemote(ctx context.Context, fz *recfz.FlipperZero, p string) ([]string, error) {
+	p = path.Clean(p)
+	if !hasMeta(p) {
+		return []string{p}, nil
+	}
+	matches := []string{"/"}
+	for _, part := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+		if _, err := path.Match(part, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		var next []string
+		for _, dir := range matches {
+			if !hasMeta(part) {
+				next = append(next, path.Join(dir, part))
+				continue
+			}
+			files, err := fz.List(ctx, dir)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				if ok, _ := path.Match(part, f.Name()); ok {
+					next = append(next, path.Join(dir, f.Name()))
+				}
+			}
+		}
+		matches = next
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s: no matches", p)
+	}
+	return matches, nil
+}
+
+// expandLocal expands the glob patterns in a local path.
+// This is only needed if the shell didn't expand the pattern, e.g. because it was quoted.
+func expandLocal(p string) ([]string, error) {
+	if !hasMeta(p) {
+		return []string{p}, nil
+	}
+	matches, err := filepath.Glob(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s: no matches", p)
+	}
+	return matches, nil
+}
+
+// hasMeta returns true if the path contains glob patterns.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[`)
+}
+
+// mkdirAllRemote creates a directory on the flipper including all missing parents.
+func mkdirAllRemote(ctx context.Context, fz *recfz.FlipperZero, p string) error {
+	fi, err := fz.Stat(ctx, p)
+	if err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if parent := path.Dir(p); parent != p && parent != "/" {
+		if err := mkdirAllRemote(ctx, fz, parent); err != nil {
+			return err
+		}
+	}
+	if err := fz.Mkdir(ctx, p); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
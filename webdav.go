@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jon4hz/fztea/recfz"
+	"golang.org/x/net/webdav"
+)
+
+// newWebDAVServer returns a http server serving the storage of the flipper over webdav.
+// If auth is set (user:password), basic authentication is required.
+// Without auth, it only listens on the loopback interface.
+func newWebDAVServer(addr, auth string, fz *recfz.FlipperZero) (*http.Server, error) {
+	if auth == "" {
+		if !isLoopback(addr) {
+			return nil, fmt.Errorf("refusing to serve webdav on %s without authentication, use --webdav-auth or listen on a loopback address", addr)
+		}
+		log.Printf("WebDAV authentication is disabled, every local user has access to the files")
+	}
+	var handler http.Handler = &webdav.Handler{
+		FileSystem: &davFS{fz: fz},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				log.Printf("webdav %s %s: %s", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	if auth != "" {
+		user, password, ok := strings.Cut(auth, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid webdav auth, must be user:password")
+		}
+		handler = basicAuth(handler, user, password)
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// isLoopback returns true if addr only listens on the loopback interface, e.g. 127.0.0.1:8080 or localhost:8080.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// basicAuth requires the given credentials for every request.
+func basicAuth(next http.Handler, user, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(u), []byte(user)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="fztea"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// davFS implements webdav.FileSystem on top of the storage of the flipper.
+// Like for sftp, the root directory is virtual and contains the storages ext and int.
+type davFS struct {
+	fz *recfz.FlipperZero
+}
+
+var _ webdav.FileSystem = (*davFS)(nil)
+
+// Mkdir creates a directory.
+func (d *davFS) Mkdir(ctx context.Context, name string, _ os.FileMode) error {
+	name = davPath(name)
+	if isVirtualDir(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	return d.fz.Mkdir(ctx, name)
+}
+
+// OpenFile opens a file or directory. Files opened for writing are written to the flipper when they are closed.
+func (d *davFS) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	name = davPath(name)
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		fi, err := d.Stat(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &davFile{ctx: ctx, fz: d.fz, name: name, info: fi}, nil
+	}
+
+	if isVirtualDir(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	f := &davFile{ctx: ctx, fz: d.fz, name: name, writable: true}
+	if flag&os.O_TRUNC == 0 {
+		var buf bytes.Buffer
+		_, err := d.fz.ReadFile(ctx, name, &buf, nil)
+		if err != nil && (!errors.Is(err, fs.ErrNotExist) || flag&os.O_CREATE == 0) {
+			return nil, err
+		}
+		f.data = buf.Bytes()
+	}
+	return f, nil
+}
+
+// RemoveAll removes a file or a directory including its content.
+func (d *davFS) RemoveAll(ctx context.Context, name string) error {
+	name = davPath(name)
+	if isVirtualDir(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	return d.fz.Remove(ctx, name, true)
+}
+
+// Rename renames or moves a file or directory.
+func (d *davFS) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = davPath(oldName), davPath(newName)
+	if isVirtualDir(oldName) || isVirtualDir(newName) {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: fs.ErrPermission}
+	}
+	return d.fz.Rename(ctx, oldName, newName)
+}
+
+// Stat returns information about a file or directory.
+func (d *davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = davPath(name)
+	if isVirtualDir(name) {
+		return virtualDir(path.Base(name)), nil
+	}
+	fi, err := d.fz.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return davInfo{fi}, nil
+}
+
+// davPath cleans a path of a webdav request.
+func davPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// davFile is a file or directory opened by the webdav handler.
+// Files are read from the flipper on the first read, the flipper doesn't support reading at an offset.
+type davFile struct {
+	ctx  context.Context
+	fz   *recfz.FlipperZero
+	name string
+	info os.FileInfo
+	pos  int64
+	// data holds the content of the file once it was read or written
+	data     []byte
+	loaded   bool
+	writable bool
+	// entries holds the remaining directory entries of Readdir
+	entries []os.FileInfo
+	listed  bool
+}
+
+// Read reads from the current position.
+func (f *davFile) Read(p []byte) (int, error) {
+	if f.info != nil && f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if !f.loaded && !f.writable {
+		var buf bytes.Buffer
+		if _, err := f.fz.ReadFile(f.ctx, f.name, &buf, nil); err != nil {
+			return 0, err
+		}
+		f.data, f.loaded = buf.Bytes(), true
+	}
+	if f.pos >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+// Write writes at the current position.
+func (f *davFile) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+	if end := f.pos + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n := copy(f.data[f.pos:], p)
+	f.pos += int64(n)
+	return n, nil
+}
+
+// Seek sets the position. It doesn't read the file, so the size can be determined cheaply.
+func (f *davFile) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+	switch whence {
+	case io.SeekCurrent:
+		base = f.pos
+	case io.SeekEnd:
+		base = f.size()
+	}
+	if base+offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.pos = base + offset
+	return f.pos, nil
+}
+
+// Readdir returns the next count entries of a directory, or all if count <= 0.
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
+	if f.info == nil || !f.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.listed {
+		if f.name == "/" {
+			f.entries = []os.FileInfo{virtualDir("ext"), virtualDir("int")}
+		} else {
+			entries, err := f.fz.List(f.ctx, f.name)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				f.entries = append(f.entries, davInfo{e})
+			}
+		}
+		f.listed = true
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+// Stat returns information about the file. For written files, the size is the size of the data written so far.
+func (f *davFile) Stat() (os.FileInfo, error) {
+	if f.writable {
+		return writtenFile{name: path.Base(f.name), size: int64(len(f.data))}, nil
+	}
+	return f.info, nil
+}
+
+// Close writes the file to the flipper if it was opened for writing.
+func (f *davFile) Close() error {
+	if !f.writable {
+		return nil
+	}
+	_, err := f.fz.WriteFile(f.ctx, f.name, bytes.NewReader(f.data), nil)
+	return err
+}
+
+// size returns the size of the file without reading it.
+func (f *davFile) size() int64 {
+	if f.loaded || f.writable {
+		return int64(len(f.data))
+	}
+	return f.info.Size()
+}
+
+// davInfo adds the content type to the information about a file.
+type davInfo struct {
+	os.FileInfo
+}
+
+var _ webdav.ContentTyper = davInfo{}
+
+// ContentType guesses the content type by the extension,
+// otherwise the webdav handler would read every file to detect it.
+func (i davInfo) ContentType(context.Context) (string, error) {
+	if t := mime.TypeByExtension(path.Ext(i.Name())); t != "" {
+		return t, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// writtenFile describes a file which is being written.
+type writtenFile struct {
+	name string
+	size int64
+}
+
+func (w writtenFile) Name() string       { return w.name }
+func (w writtenFile) Size() int64        { return w.size }
+func (w writtenFile) Mode() fs.FileMode  { return 0o644 }
+func (w writtenFile) ModTime() time.Time { return time.Time{} }
+func (w writtenFile) IsDir() bool        { return false }
+func (w writtenFile) Sys() any           { return nil }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"[::]:8080":      false,
+		"10.0.0.2:8080":  false,
+		"lab-pi:8080":    false,
+		"8080":           false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestWebDAVRequiresAuth(t *testing.T) {
+	if _, err := newWebDAVServer(":8080", "", nil); err == nil {
+		t.Fatal("expected an error serving on all interfaces without auth")
+	}
+	if _, err := newWebDAVServer("127.0.0.1:8080", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newWebDAVServer(":8080", "dolphin", nil); err == nil {
+		t.Fatal("expected an error for auth without password")
+	}
+
+	srv, err := newWebDAVServer(":8080", "dolphin:secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		user, password string
+	}{
+		{"", ""},
+		{"dolphin", "wrong"},
+		{"wrong", "secret"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s:%s: expected status %d, got %d", tc.user, tc.password, http.StatusUnauthorized, rec.Code)
+		}
+	}
+}